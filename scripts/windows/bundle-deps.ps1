@@ -327,13 +327,20 @@ try {
   $nxfJar = Join-Path $nxfDest "nextflow.jar"
   Extract-EmbeddedZipPayload $distPath $nxfJar
 
-  $wrapperSrc = Join-Path $repoRoot "scripts\\windows\\nextflow-wrapper\\main.go"
-  if (-not (Test-Path $wrapperSrc)) {
+  $wrapperSrc = Join-Path $repoRoot "scripts\\windows\\nextflow-wrapper"
+  if (-not (Test-Path (Join-Path $wrapperSrc "go.mod"))) {
     throw "Missing nextflow wrapper source: $wrapperSrc"
   }
   $wrapperExe = Join-Path $nxfDest "nextflow.exe"
   Write-Host "Building nextflow.exe wrapper..."
-  & go build -o $wrapperExe $wrapperSrc
+  Push-Location $wrapperSrc
+  try {
+    $wrapperVersion = (git describe --tags --always --dirty 2>$null)
+    if (-not $wrapperVersion) { $wrapperVersion = "dev" }
+    & go build -ldflags "-X main.version=$wrapperVersion" -o $wrapperExe .
+  } finally {
+    Pop-Location
+  }
   if (-not (Test-Path $wrapperExe)) {
     throw "Failed to build nextflow.exe wrapper at: $wrapperExe"
   }
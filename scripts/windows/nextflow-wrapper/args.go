@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Wrapper modes replace the launch altogether. Each only triggers as the
+// first argument and cannot be combined with other wrapper flags.
+const (
+	doctorMode  = "--wrapper-doctor"
+	versionMode = "--wrapper-version"
+	envMode     = "--wrapper-env"
+)
+
+const dryRunFlag = "--wrapper-dry-run"
+
+// wrapperFlags are the wrapper's own flags, consumed before Nextflow's.
+type wrapperFlags struct {
+	mode      string // doctorMode, versionMode, envMode, or "" to launch
+	dryRun    bool
+	tracePath string
+}
+
+func isWrapperMode(arg string) bool {
+	return arg == doctorMode || arg == versionMode || arg == envMode
+}
+
+func isWrapperFlag(arg string) bool {
+	return isWrapperMode(arg) || arg == dryRunFlag || strings.HasPrefix(arg, execTraceFlag)
+}
+
+// parseWrapperFlags takes the wrapper's own flags from the front of args and
+// returns them with the remaining arguments for Nextflow. A mode must come
+// first and alone; --wrapper-dry-run and --wrapper-exec-trace= may be given
+// together in either order.
+func parseWrapperFlags(args []string) (wrapperFlags, []string, error) {
+	var flags wrapperFlags
+	if len(args) > 0 && isWrapperMode(args[0]) {
+		if len(args) > 1 && isWrapperFlag(args[1]) {
+			return flags, nil, fmt.Errorf("%s cannot be combined with %s", args[0], args[1])
+		}
+		flags.mode = args[0]
+		return flags, args[1:], nil
+	}
+	for ; len(args) > 0; args = args[1:] {
+		switch arg := args[0]; {
+		case arg == dryRunFlag:
+			flags.dryRun = true
+		case strings.HasPrefix(arg, execTraceFlag):
+			path := strings.TrimPrefix(arg, execTraceFlag)
+			if path == "" {
+				return flags, nil, fmt.Errorf("%s needs a file to write the trace to, as in %strace.cmd", execTraceFlag, execTraceFlag)
+			}
+			if flags.tracePath != "" {
+				return flags, nil, fmt.Errorf("%s given more than once", execTraceFlag)
+			}
+			flags.tracePath = path
+		case isWrapperMode(arg):
+			return flags, nil, fmt.Errorf("%s must be the first argument and cannot be combined with other wrapper flags", arg)
+		default:
+			return flags, args, nil
+		}
+	}
+	return flags, args, nil
+}
+
+// splitArgs splits s on whitespace, keeping single- or double-quoted segments
+// together. Backslashes are taken literally so Windows paths survive intact.
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// expandArgFiles replaces each @path argument with the lines of that file,
+// one argument per line, skipping blanks and # comments. This lets callers
+// pass long parameter lists without hitting the Windows command-line limit.
+func expandArgFiles(args []string) ([]string, error) {
+	var out []string
+	for _, arg := range args {
+		path, ok := strings.CutPrefix(arg, "@")
+		if !ok || path == "" {
+			out = append(out, arg)
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read argument file %s: %w", path, err)
+		}
+		text := strings.TrimPrefix(string(data), "\ufeff")
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			out = append(out, line)
+		}
+	}
+	return out, nil
+}
+
+var argVarPattern = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_]*)%|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandArgVars substitutes %VAR% and ${VAR} references in args from the
+// environment. Unset variables expand to "" with a warning so typos show up.
+func (w *wrapper) expandArgVars(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = argVarPattern.ReplaceAllStringFunc(arg, func(ref string) string {
+			m := argVarPattern.FindStringSubmatch(ref)
+			name := m[1] + m[2]
+			value, ok := w.env.lookup(name)
+			if !ok {
+				w.logf("warning: %s is not set; expanding %s to empty in %q", name, ref, arg)
+			}
+			return value
+		})
+	}
+	return out
+}
+
+// nextflowArgs assembles everything after -jar nextflow.jar, in this order:
+//
+//  1. BIOVAULT_ARGS_PREPEND, such as a default -log path or subcommand
+//  2. the caller's forwarded arguments
+//  3. BIOVAULT_NEXTFLOW_EXTRA_ARGS
+//
+// When the prepend ends with a subcommand that the forwarded arguments already
+// start with, it is not repeated: BIOVAULT_ARGS_PREPEND="-log x.log run" with
+// "run main.nf" gives "-log x.log run main.nf".
+func (w *wrapper) nextflowArgs(forwarded []string) ([]string, error) {
+	prepend, err := splitArgs(w.env.get("BIOVAULT_ARGS_PREPEND"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BIOVAULT_ARGS_PREPEND: %w", err)
+	}
+	extra, err := splitArgs(w.env.get("BIOVAULT_NEXTFLOW_EXTRA_ARGS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BIOVAULT_NEXTFLOW_EXTRA_ARGS: %w", err)
+	}
+
+	if n := len(prepend); n > 0 && len(forwarded) > 0 {
+		if last := prepend[n-1]; !strings.HasPrefix(last, "-") && last == forwarded[0] {
+			prepend = prepend[:n-1]
+		}
+	}
+	args := append(prepend, forwarded...)
+	return append(args, extra...), nil
+}
+
+// strictPathFlags are the Nextflow parameters whose values
+// BIOVAULT_STRICT_PATHS checks.
+var strictPathFlags = []string{"--input", "--outdir"}
+
+// checkPaths implements BIOVAULT_STRICT_PATHS: it fails if the value of any
+// strictPathFlags parameter, given as "--flag value" or "--flag=value", has a
+// parent directory that does not exist. Remote paths such as s3:// URLs are
+// not checked. Relative values are resolved against dir, the child's working
+// directory, when it is set.
+func checkPaths(args []string, dir string) error {
+	for i := 0; i < len(args); i++ {
+		for _, flag := range strictPathFlags {
+			value, ok := strings.CutPrefix(args[i], flag+"=")
+			if !ok && args[i] == flag && i+1 < len(args) {
+				i++
+				value, ok = args[i], true
+			}
+			if !ok || value == "" || strings.Contains(value, "://") {
+				continue
+			}
+			parent := filepath.Dir(value)
+			if dir != "" && !filepath.IsAbs(parent) {
+				parent = filepath.Join(dir, parent)
+			}
+			info, err := os.Stat(parent)
+			if err != nil || !info.IsDir() {
+				return fmt.Errorf("BIOVAULT_STRICT_PATHS: %s %s: parent directory %s does not exist", flag, value, parent)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWrapperFlags(t *testing.T) {
+	tests := []struct {
+		args  []string
+		flags wrapperFlags
+		rest  []string
+	}{
+		{nil, wrapperFlags{}, nil},
+		{[]string{"run", "hello"}, wrapperFlags{}, []string{"run", "hello"}},
+		{[]string{"--wrapper-dry-run", "--wrapper-exec-trace=t.cmd", "run"}, wrapperFlags{dryRun: true, tracePath: "t.cmd"}, []string{"run"}},
+		{[]string{"--wrapper-exec-trace=t.cmd", "--wrapper-dry-run", "run"}, wrapperFlags{dryRun: true, tracePath: "t.cmd"}, []string{"run"}},
+		{[]string{"--wrapper-doctor"}, wrapperFlags{mode: doctorMode}, []string{}},
+		{[]string{"--wrapper-version", "run"}, wrapperFlags{mode: versionMode}, []string{"run"}},
+		{[]string{"--wrapper-env"}, wrapperFlags{mode: envMode}, []string{}},
+		// Once Nextflow's arguments start, nothing more is taken.
+		{[]string{"run", "--wrapper-dry-run"}, wrapperFlags{}, []string{"run", "--wrapper-dry-run"}},
+		{[]string{"run", "--wrapper-version"}, wrapperFlags{}, []string{"run", "--wrapper-version"}},
+	}
+	for _, tt := range tests {
+		flags, rest, err := parseWrapperFlags(tt.args)
+		if err != nil {
+			t.Errorf("parseWrapperFlags(%q): %v", tt.args, err)
+			continue
+		}
+		if flags != tt.flags || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("parseWrapperFlags(%q) = %+v, %q; want %+v, %q", tt.args, flags, rest, tt.flags, tt.rest)
+		}
+	}
+}
+
+func TestParseWrapperFlagsRejects(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--wrapper-dry-run", "--wrapper-version"}, "must be the first argument"},
+		{[]string{"--wrapper-exec-trace=t.cmd", "--wrapper-doctor"}, "must be the first argument"},
+		{[]string{"--wrapper-version", "--wrapper-env"}, "cannot be combined with --wrapper-env"},
+		{[]string{"--wrapper-doctor", "--wrapper-dry-run"}, "cannot be combined with --wrapper-dry-run"},
+		{[]string{"--wrapper-exec-trace=", "run"}, "needs a file"},
+		{[]string{"--wrapper-dry-run", "--wrapper-exec-trace="}, "needs a file"},
+		{[]string{"--wrapper-exec-trace=a", "--wrapper-exec-trace=b"}, "given more than once"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseWrapperFlags(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseWrapperFlags(%q) error = %v, want one containing %q", tt.args, err, tt.err)
+		}
+	}
+}
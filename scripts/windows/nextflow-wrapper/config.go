@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const configFileName = "wrapper.toml"
+
+var configKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// applyConfig reads wrapper.toml next to the executable and adds each entry
+// to the environment unless the variable is already set, so the precedence
+// is environment, then file, then built-in defaults. Keys are variable names
+// such as BIOVAULT_JAVA_XMX or NXF_HOME. Valid entries are applied even when
+// other lines are malformed; the returned error lists the bad lines.
+func (w *wrapper) applyConfig(exeDir string) error {
+	path := filepath.Join(exeDir, configFileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	entries, err := parseConfig(string(data))
+	for _, kv := range entries {
+		key, value, _ := strings.Cut(kv, "=")
+		w.env = w.env.withDefault(key, value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseConfig parses the subset of TOML the wrapper needs: top-level
+// KEY = value lines, where value is a "basic string", a 'literal string' or a
+// bare word such as 4g or true. Blank lines and # comments are skipped.
+func parseConfig(text string) ([]string, error) {
+	var entries []string
+	var errs []string
+	text = strings.TrimPrefix(text, "\ufeff")
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !configKeyPattern.MatchString(key) {
+			errs = append(errs, fmt.Sprintf("line %d: expected KEY = value, got %q", i+1, line))
+			continue
+		}
+		value, err := configValue(strings.TrimSpace(raw))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s: %v", i+1, key, err))
+			continue
+		}
+		entries = append(entries, key+"="+value)
+	}
+	if len(errs) > 0 {
+		return entries, errors.New(strings.Join(errs, "; "))
+	}
+	return entries, nil
+}
+
+func configValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := closingQuote(raw)
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected text after string: %q", rest)
+		}
+		value, err := strconv.Unquote(raw[:end+1])
+		if err != nil {
+			return "", errors.New(`invalid escape in "double-quoted" string; for Windows paths use 'single quotes' or double the backslashes`)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		if rest := strings.TrimSpace(raw[end+2:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected text after string: %q", rest)
+		}
+		return raw[1 : end+1], nil
+	}
+	value, _, _ := strings.Cut(raw, "#")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("missing value")
+	}
+	return value, nil
+}
+
+// closingQuote returns the index of the double quote ending the basic string
+// at the start of s, skipping backslash escapes, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"bare word", "BIOVAULT_JAVA_XMX = 4g", []string{"BIOVAULT_JAVA_XMX=4g"}},
+		{"no spaces", "A=b", []string{"A=b"}},
+		{"basic string", `NXF_HOME = "/opt/nf home"`, []string{"NXF_HOME=/opt/nf home"}},
+		{"escaped backslashes", `NXF_HOME = "C:\\Users\\me\\nf"`, []string{`NXF_HOME=C:\Users\me\nf`}},
+		{"escaped quote", `A = "say \"hi\""`, []string{`A=say "hi"`}},
+		{"literal string", `NXF_HOME = 'C:\Users\me\nf'`, []string{`NXF_HOME=C:\Users\me\nf`}},
+		{"hash inside quotes", `A = "x # y"`, []string{"A=x # y"}},
+		{"hash inside literal", `A = 'x # y'`, []string{"A=x # y"}},
+		{"trailing comment bare", "A = 4g # heap", []string{"A=4g"}},
+		{"trailing comment basic", `A = "4g" # heap`, []string{"A=4g"}},
+		{"trailing comment literal", `A = '4g' # heap`, []string{"A=4g"}},
+		{"empty string", `A = ""`, []string{"A="}},
+		{"comments and blanks", "# header\n\n  # indented\nA = 1\r\nB = 2\n", []string{"A=1", "B=2"}},
+		{"byte order mark", "\ufeffA = 1", []string{"A=1"}},
+		{"equals in value", `A = "k=v"`, []string{"A=k=v"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConfig(tt.text)
+			if err != nil {
+				t.Fatalf("parseConfig(%q): %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseConfig(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"no equals", "BIOVAULT_JAVA_XMX 4g", `line 1: expected KEY = value, got "BIOVAULT_JAVA_XMX 4g"`},
+		{"bad key", "1A = b", "line 1: expected KEY = value"},
+		{"missing value", "A =", "line 1: A: missing value"},
+		{"comment only value", "A = # nothing", "line 1: A: missing value"},
+		{"unterminated basic", `A = "open`, "line 1: A: unterminated string"},
+		{"unterminated literal", `A = 'open`, "line 1: A: unterminated string"},
+		{"text after string", `A = "x" y`, `line 1: A: unexpected text after string: "y"`},
+		{"windows path in basic string", `NXF_HOME = "C:\Users\me\nf"`, "line 1: NXF_HOME: invalid escape in \"double-quoted\" string; for Windows paths use 'single quotes' or double the backslashes"},
+		{"reports every bad line", "A = 1\nB\nC = 'x", "line 2: expected KEY = value, got \"B\"; line 3: C: unterminated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig(tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseConfig(%q) error = %v, want one containing %q", tt.text, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseConfigKeepsValidLines(t *testing.T) {
+	got, err := parseConfig("A = 1\nbroken\nB = '2'")
+	if err == nil {
+		t.Error("a malformed line was not reported")
+	}
+	if want := []string{"A=1", "B=2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConfig kept %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// useUTF8Console is a no-op outside Windows, where terminals are UTF-8 already.
+func useUTF8Console() (restore func()) {
+	return func() {}
+}
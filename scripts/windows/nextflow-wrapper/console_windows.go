@@ -0,0 +1,28 @@
+package main
+
+const utf8CodePage = 65001
+
+var (
+	procGetConsoleCP       = kernel32.NewProc("GetConsoleCP")
+	procGetConsoleOutputCP = kernel32.NewProc("GetConsoleOutputCP")
+	procSetConsoleCP       = kernel32.NewProc("SetConsoleCP")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+)
+
+// useUTF8Console switches the console's input and output code pages to UTF-8
+// so non-ASCII Nextflow output is not garbled, and returns a function that
+// restores the original code pages. Without a console it does nothing.
+func useUTF8Console() (restore func()) {
+	in, _, _ := procGetConsoleCP.Call()
+	out, _, _ := procGetConsoleOutputCP.Call()
+	if in == 0 || out == 0 {
+		return func() {}
+	}
+
+	procSetConsoleCP.Call(utf8CodePage)
+	procSetConsoleOutputCP.Call(utf8CodePage)
+	return func() {
+		procSetConsoleCP.Call(in)
+		procSetConsoleOutputCP.Call(out)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// debugEnvKeys are the variables, besides BIOVAULT_*, that most often explain
+// a launch problem.
+var debugEnvKeys = []string{"JAVA_HOME", "JAVA_TOOL_OPTIONS", "NXF_HOME", "NXF_OFFLINE", "NXF_OPTS", "NXF_TEMP", "PATH"}
+
+// logPrefix tags every line the wrapper itself writes to stderr, so its
+// messages cannot be mistaken for Nextflow's, which pass through untouched.
+const logPrefix = "biovault-wrapper: "
+
+func (w *wrapper) logf(format string, args ...any) {
+	fmt.Fprintf(w.stderr, logPrefix+format+"\n", args...)
+}
+
+func (w *wrapper) debugf(format string, args ...any) {
+	if !w.debug {
+		return
+	}
+	w.logf("debug: "+format, args...)
+}
+
+func (w *wrapper) debugCommand(cmd *exec.Cmd) {
+	if !w.debug {
+		return
+	}
+
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	w.debugf("java: %s", cmd.Path)
+	w.debugf("args: %q", cmd.Args[1:])
+	w.debugf("dir: %s", dir)
+
+	for _, key := range debugEnvKeys {
+		if value, ok := environ(cmd.Env).lookup(key); ok {
+			w.debugf("env: %s", w.scrub(key+"="+value))
+		}
+	}
+	for _, kv := range cmd.Env {
+		if strings.HasPrefix(strings.ToUpper(kv), "BIOVAULT_") {
+			w.debugf("env: %s", w.scrub(kv))
+		}
+	}
+}
+
+// debugArgs logs each Nextflow argument on its own line, unquoted and
+// delimited by brackets, so embedded spaces can be confirmed by eye.
+func (w *wrapper) debugArgs(args []string) {
+	for i, arg := range args {
+		w.debugf("nextflow arg %d: [%s]", i, arg)
+	}
+}
+
+// candidate is existingFile for resolution candidates. In debug mode it also
+// logs why a path was rejected, so a failed lookup reads as a list of every
+// path tried; otherwise it is exactly existingFile.
+func (w *wrapper) candidate(path string) bool {
+	if !w.debug {
+		return existingFile(path)
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		w.debugf("tried %s (missing)", path)
+	case errors.Is(err, fs.ErrPermission):
+		w.debugf("tried %s (permission denied)", path)
+	case err != nil:
+		w.debugf("tried %s (%v)", path, err)
+	case info.IsDir():
+		w.debugf("tried %s (is a directory)", path)
+	default:
+		w.debugf("tried %s (found)", path)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"runtime"
+	"time"
+)
+
+// diagnostic is the report written to BIOVAULT_DIAG_FILE when Nextflow cannot
+// be launched. Fields may be added but existing ones keep their names and
+// meaning; bump diagnosticSchema if that ever has to change.
+type diagnostic struct {
+	Schema    int    `json:"schema"`
+	Time      string `json:"time"`
+	Error     string `json:"error"`
+	Java      string `json:"java"`
+	JavaFound bool   `json:"javaFound"`
+	Jar       string `json:"jar"`
+	JarFound  bool   `json:"jarFound"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+const diagnosticSchema = 1
+
+// writeDiagnostic is best-effort; failing to write the report never changes
+// the wrapper's own error output or exit code.
+func (w *wrapper) writeDiagnostic(java, jar string, launchErr error) {
+	path := w.env.get("BIOVAULT_DIAG_FILE")
+	if path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(diagnostic{
+		Schema:    diagnosticSchema,
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Error:     launchErr.Error(),
+		Java:      java,
+		JavaFound: javaExists(java),
+		Jar:       jar,
+		JarFound:  existingFile(jar),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, append(data, '\n'), 0o644)
+}
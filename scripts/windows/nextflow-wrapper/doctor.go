@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// doctor runs every resolution and environment check without launching a
+// pipeline, printing PASS/FAIL per check with a hint for each failure. It
+// returns 0 only if all of them pass.
+func (w *wrapper) doctor() int {
+	failed := false
+	report := func(name string, err error, hint string) {
+		if err == nil {
+			fmt.Fprintf(w.stdout, "[PASS] %s\n", name)
+			return
+		}
+		failed = true
+		fmt.Fprintf(w.stdout, "[FAIL] %s: %v\n", name, err)
+		if hint != "" {
+			fmt.Fprintf(w.stdout, "       %s\n", hint)
+		}
+	}
+
+	exeDir, err := resolveExeDir()
+	report("wrapper executable path", err, "Reinstall biovault-desktop.")
+	if err != nil {
+		return exitWrapperError
+	}
+
+	if existingFile(filepath.Join(exeDir, configFileName)) {
+		report(configFileName+" parses", w.applyConfig(exeDir), "Fix or remove the listed lines; write values as KEY = 'value', or double any backslashes in KEY = \"value\".")
+	}
+
+	jar, err := w.resolveJar(exeDir)
+	report("nextflow.jar present: "+jar, err, "Reinstall biovault-desktop, or set BIOVAULT_NEXTFLOW_JAR to a valid jar.")
+	if err == nil {
+		name := "nextflow.jar checksum"
+		if !existingFile(jar + ".sha256") {
+			name += " (no .sha256 sidecar, skipped)"
+		}
+		report(name, verifyJarChecksum(jar), "The jar is corrupt or incomplete; reinstall biovault-desktop.")
+		report("nextflow.jar is a valid jar", verifyJarArchive(jar), "The jar is corrupt or incomplete; reinstall biovault-desktop.")
+	}
+
+	java, _ := w.resolveJava(exeDir, jar)
+	_, err = w.probeJava(java)
+	report("java runnable: "+java, err, "Install Java 17 or newer, or reinstall biovault-desktop to restore the bundled runtime.")
+	if err == nil {
+		report("java version", w.checkJavaVersion(java), "Install a newer Java and set JAVA_HOME, or reinstall biovault-desktop.")
+	}
+
+	env := w.childEnv(exeDir)
+	for _, key := range []string{"NXF_HOME", "NXF_TEMP"} {
+		dir := env.get(key)
+		if dir == "" {
+			continue
+		}
+		report(fmt.Sprintf("write access to %s: %s", key, dir), checkWritable(dir), "Set "+key+" to a directory you can write to.")
+	}
+
+	if failed {
+		return exitWrapperError
+	}
+	return 0
+}
+
+func (w *wrapper) checkJavaVersion(java string) error {
+	min, err := w.minJava()
+	if err != nil {
+		return err
+	}
+	major, ok := w.javaMajor(java)
+	if !ok {
+		return errors.New("could not determine the version from java -version")
+	}
+	if major < min {
+		return fmt.Errorf("java %d is older than the required java %d", major, min)
+	}
+	return nil
+}
+
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".wrapper-write-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
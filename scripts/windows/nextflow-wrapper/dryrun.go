@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+func (w *wrapper) printDryRun(java, jar string, args []string) {
+	fmt.Fprintf(w.stdout, "java: %s (%s)\n", java, presence(javaExists(java)))
+	fmt.Fprintf(w.stdout, "jar:  %s (%s)\n", jar, presence(existingFile(jar)))
+	fmt.Fprintln(w.stdout, formatCommand(runtime.GOOS, java, args))
+}
+
+// formatCommand renders java and args as a single line that can be pasted into
+// a shell on goos: a cmd.exe prompt on Windows (not PowerShell, which would
+// need the & call operator), POSIX sh elsewhere.
+func formatCommand(goos, java string, args []string) string {
+	if goos == "windows" {
+		return cmdCommand(java, args, false)
+	}
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, shellQuote(java))
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// cmdMeta are the characters cmd.exe itself interprets on a command line.
+const cmdMeta = `()%!^"<>&|`
+
+// cmdCommand renders a command line for cmd.exe: an interactive prompt, or a
+// batch file when batch is set, where % must be written %% instead of ^%.
+// Each argument is quoted for CommandLineToArgvW and then every cmd.exe
+// metacharacter, quotes included, is escaped with ^, so cmd.exe hands the
+// text to java unchanged regardless of its own quote state.
+func cmdCommand(java string, args []string, batch bool) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, cmdPath(java, batch))
+	for _, arg := range args {
+		parts = append(parts, cmdEscape(windowsQuote(arg), batch))
+	}
+	return strings.Join(parts, " ")
+}
+
+// cmdPath quotes a path for cmd.exe, where it cannot be caret-escaped like an
+// argument because cmd.exe needs real quotes to find a program whose path has
+// spaces. Metacharacters other than % are literal inside quotes; % is escaped
+// outside them.
+func cmdPath(path string, batch bool) string {
+	if !strings.ContainsAny(path, " \t"+cmdMeta) {
+		return path
+	}
+	if batch {
+		return `"` + strings.ReplaceAll(path, "%", "%%") + `"`
+	}
+	return `"` + strings.ReplaceAll(path, "%", `"^%"`) + `"`
+}
+
+func cmdEscape(s string, batch bool) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '%' && batch:
+			b.WriteString("%%")
+		case strings.ContainsRune(cmdMeta, c):
+			b.WriteByte('^')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// windowsQuote follows the CommandLineToArgvW rules: backslashes are literal
+// unless they precede a double quote, in which case they must be doubled.
+func windowsQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for _, c := range arg {
+		switch c {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteRune(c)
+	}
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestFormatCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		goos string
+		java string
+		args []string
+		want string
+	}{
+		{
+			name: "posix plain",
+			goos: "linux",
+			java: "/opt/java/bin/java",
+			args: []string{"-jar", "/opt/nf/nextflow.jar", "run"},
+			want: "/opt/java/bin/java -jar /opt/nf/nextflow.jar run",
+		},
+		{
+			name: "posix quoting",
+			goos: "darwin",
+			java: "/Applications/Bio Vault/java",
+			args: []string{"it's", "R&D", "$HOME"},
+			want: `'/Applications/Bio Vault/java' 'it'\''s' 'R&D' '$HOME'`,
+		},
+		{
+			name: "cmd plain",
+			goos: "windows",
+			java: `C:\bv\java.exe`,
+			args: []string{"-jar", `C:\nf\nextflow.jar`},
+			want: `C:\bv\java.exe -jar C:\nf\nextflow.jar`,
+		},
+		{
+			name: "cmd program with spaces",
+			goos: "windows",
+			java: `C:\Program Files\BioVault\java.exe`,
+			args: []string{"run"},
+			want: `"C:\Program Files\BioVault\java.exe" run`,
+		},
+		{
+			name: "cmd metacharacters",
+			goos: "windows",
+			java: `C:\bv\java.exe`,
+			args: []string{"R&D", "a|b", "(x)", "%PATH%", "a^b", "<in>"},
+			want: `C:\bv\java.exe R^&D a^|b ^(x^) ^%PATH^% a^^b ^<in^>`,
+		},
+		{
+			name: "cmd spaces and quotes",
+			goos: "windows",
+			java: `C:\bv\java.exe`,
+			args: []string{`C:\My Runs\`, `say "hi"`, ""},
+			want: `C:\bv\java.exe ^"C:\My Runs\\^" ^"say \^"hi\^"^" ^"^"`,
+		},
+		{
+			name: "cmd percent in program",
+			goos: "windows",
+			java: `C:\100%\java.exe`,
+			want: `"C:\100"^%"\java.exe"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatCommand(tt.goos, tt.java, tt.args); got != tt.want {
+				t.Errorf("formatCommand() =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCmdCommandBatch(t *testing.T) {
+	got := cmdCommand(`C:\100%\java.exe`, []string{"%TEMP%", "R&D"}, true)
+	want := `"C:\100%%\java.exe" %%TEMP%% R^&D`
+	if got != want {
+		t.Errorf("cmdCommand() = %s, want %s", got, want)
+	}
+}
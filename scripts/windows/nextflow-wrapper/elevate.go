@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// elevatedFlag marks a relaunched elevated wrapper so it never tries to
+// elevate again. Its value is the elevation spec file, and it is consumed
+// before any other argument handling.
+const elevatedFlag = "--wrapper-elevated="
+
+var errElevationDeclined = errors.New("elevation was declined")
+
+// elevationSpec carries what ShellExecuteEx cannot across the UAC boundary:
+// the elevated process starts with a fresh environment and in System32, so
+// the original environment and working directory are written to a temp file
+// along with the arguments.
+type elevationSpec struct {
+	Args []string `json:"args"`
+	Env  []string `json:"env"`
+	Dir  string   `json:"dir"`
+}
+
+// elevate relaunches the wrapper with administrator rights when
+// BIOVAULT_ELEVATE=auto and the jar's directory cannot be written to, which
+// happens when biovault-desktop is installed under Program Files. It reports
+// whether it relaunched, and if so the exit code to terminate with. The
+// elevated process runs in its own console window and its output is not
+// relayed: only its exit code is seen here. Elevation is only supported on
+// Windows; elsewhere this does nothing.
+func (w *wrapper) elevate(jar string, args []string) (code int, relaunched bool) {
+	if !canElevate || w.elevated || !strings.EqualFold(w.env.get("BIOVAULT_ELEVATE"), "auto") {
+		return 0, false
+	}
+	dir := filepath.Dir(jar)
+	err := checkWritable(dir)
+	if err == nil || !errors.Is(err, fs.ErrPermission) {
+		return 0, false
+	}
+
+	w.debugf("%s is not writable (%v); relaunching elevated", dir, err)
+	spec, err := w.writeElevationSpec(args)
+	if err != nil {
+		w.logf("failed to relaunch elevated: %v", err)
+		return exitWrapperError, true
+	}
+	defer os.Remove(spec)
+	w.logf("relaunching with administrator rights; nextflow's output appears in the new console window")
+	code, err = relaunchElevated([]string{elevatedFlag + spec})
+	switch {
+	case errors.Is(err, errElevationDeclined):
+		w.logf("Administrator rights are needed to write to %s, but elevation was declined.", dir)
+		return exitElevationDeclined, true
+	case err != nil:
+		w.logf("failed to relaunch elevated: %v", err)
+		return exitWrapperError, true
+	}
+	return code, true
+}
+
+// writeElevationSpec saves args with the wrapper's environment and working
+// directory to a temp file and returns its path.
+func (w *wrapper) writeElevationSpec(args []string) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(elevationSpec{Args: args, Env: w.env, Dir: dir})
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "biovault-elevate-*.json")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// readElevationSpec loads and deletes the spec written by writeElevationSpec,
+// restores its environment and working directory, and returns its arguments.
+func (w *wrapper) readElevationSpec(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read elevation spec: %w", err)
+	}
+	os.Remove(path)
+	var spec elevationSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid elevation spec %s: %w", path, err)
+	}
+	if err := os.Chdir(spec.Dir); err != nil {
+		return nil, fmt.Errorf("failed to restore the working directory: %w", err)
+	}
+	w.env = spec.Env
+	w.elevated = true
+	return spec.Args, nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+const canElevate = false
+
+func relaunchElevated(args []string) (int, error) {
+	return 0, errors.New("elevation is only supported on Windows")
+}
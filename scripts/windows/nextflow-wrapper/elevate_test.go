@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestElevationSpecRoundTrip checks that the elevated process gets back the
+// arguments, environment and working directory UAC would otherwise drop.
+func TestElevationSpecRoundTrip(t *testing.T) {
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(orig) })
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"run", "main.nf", "-params-file", "params.json"}
+	env := testEnv("BIOVAULT_ELEVATE=auto", "NXF_HOME=C:\\Users\\me\\.nextflow")
+	w := &wrapper{env: env, stderr: &bytes.Buffer{}}
+	spec, err := w.writeElevationSpec(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(spec) })
+	if err := os.Chdir(orig); err != nil {
+		t.Fatal(err)
+	}
+
+	elevated := &wrapper{env: testEnv(), stderr: &bytes.Buffer{}}
+	got, err := elevated.readElevationSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, args) {
+		t.Errorf("args = %q, want %q", got, args)
+	}
+	if !reflect.DeepEqual(elevated.env, environ(env)) {
+		t.Errorf("env = %q, want %q", elevated.env, env)
+	}
+	if !elevated.elevated {
+		t.Error("the relaunched wrapper is not marked elevated")
+	}
+	if cwd, _ := os.Getwd(); cwd != dir {
+		t.Errorf("working directory = %s, want %s", cwd, dir)
+	}
+	if _, err := os.Stat(spec); !os.IsNotExist(err) {
+		t.Errorf("the spec file was not removed: %v", err)
+	}
+}
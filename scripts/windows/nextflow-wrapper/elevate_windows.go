@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const canElevate = true
+
+const (
+	seeMaskNoCloseProcess = 0x00000040
+	swShowNormal          = 1
+	errorCancelled        = syscall.Errno(1223)
+)
+
+var procShellExecuteExW = syscall.NewLazyDLL("shell32.dll").NewProc("ShellExecuteExW")
+
+// shellExecuteInfo mirrors SHELLEXECUTEINFOW.
+type shellExecuteInfo struct {
+	cbSize       uint32
+	fMask        uint32
+	hwnd         uintptr
+	lpVerb       *uint16
+	lpFile       *uint16
+	lpParameters *uint16
+	lpDirectory  *uint16
+	nShow        int32
+	hInstApp     uintptr
+	lpIDList     uintptr
+	lpClass      *uint16
+	hkeyClass    uintptr
+	dwHotKey     uint32
+	hIcon        uintptr
+	hProcess     syscall.Handle
+}
+
+// relaunchElevated runs the wrapper executable again through ShellExecuteEx
+// with the "runas" verb, which shows the UAC prompt, and waits for it to
+// exit. A cancelled prompt is reported as errElevationDeclined.
+func relaunchElevated(args []string) (int, error) {
+	exe, err := executable()
+	if err != nil {
+		return 0, err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return 0, err
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = windowsQuote(arg)
+	}
+
+	info := shellExecuteInfo{
+		fMask:        seeMaskNoCloseProcess,
+		lpVerb:       syscall.StringToUTF16Ptr("runas"),
+		lpFile:       syscall.StringToUTF16Ptr(exe),
+		lpParameters: syscall.StringToUTF16Ptr(strings.Join(quoted, " ")),
+		lpDirectory:  syscall.StringToUTF16Ptr(dir),
+		nShow:        swShowNormal,
+	}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	if ok, _, err := procShellExecuteExW.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		if err == errorCancelled {
+			return 0, errElevationDeclined
+		}
+		return 0, err
+	}
+	defer syscall.CloseHandle(info.hProcess)
+
+	if _, err := syscall.WaitForSingleObject(info.hProcess, syscall.INFINITE); err != nil {
+		return 0, err
+	}
+	var code uint32
+	if err := syscall.GetExitCodeProcess(info.hProcess, &code); err != nil {
+		return 0, err
+	}
+	return int(code), nil
+}
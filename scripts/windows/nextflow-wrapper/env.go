@@ -0,0 +1,65 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+)
+
+// environ is a KEY=VALUE list in the form returned by os.Environ. Lookups
+// follow exec.Cmd semantics: the last entry for a key wins, and keys are
+// case-insensitive on Windows.
+type environ []string
+
+func (e environ) lookup(key string) (string, bool) {
+	for i := len(e) - 1; i >= 0; i-- {
+		k, v, ok := strings.Cut(e[i], "=")
+		if ok && envKeyEqual(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (e environ) get(key string) string {
+	v, _ := e.lookup(key)
+	return v
+}
+
+func envKeyEqual(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// enabled reports whether an opt-in flag such as BIOVAULT_WRAPPER_DEBUG=1 is
+// switched on.
+func (e environ) enabled(key string) bool {
+	switch strings.ToLower(e.get(key)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// withDefault returns e with key=value appended if key is not already set.
+// The receiver is never modified.
+func (e environ) withDefault(key, value string) environ {
+	if _, ok := e.lookup(key); ok {
+		return e
+	}
+	return append(e[:len(e):len(e)], key+"="+value)
+}
+
+// with returns e with key set to value, replacing any existing entries for
+// key. The receiver is never modified.
+func (e environ) with(key, value string) environ {
+	out := make(environ, 0, len(e)+1)
+	for _, kv := range e {
+		if k, _, ok := strings.Cut(kv, "="); ok && envKeyEqual(k, key) {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return append(out, key+"="+value)
+}
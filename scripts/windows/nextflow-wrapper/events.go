@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"syscall"
+)
+
+// Lifecycle events written to BIOVAULT_EVENT_PIPE, one JSON object per line.
+type (
+	resolvedEvent struct {
+		Event string `json:"event"`
+		Java  string `json:"java"`
+		Jar   string `json:"jar"`
+	}
+	startedEvent struct {
+		Event string `json:"event"`
+		PID   int    `json:"pid"`
+	}
+	exitedEvent struct {
+		Event      string `json:"event"`
+		Code       int    `json:"code"`
+		DurationMs int64  `json:"durationMs"`
+	}
+)
+
+// eventPipe is the BIOVAULT_EVENT_PIPE writer. Events are best-effort: a nil
+// eventPipe, or one whose reader has gone away, silently drops them.
+type eventPipe struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+// openEventPipe opens the FIFO or Windows named pipe for writing. On Unix the
+// open is non-blocking, so a pipe nobody is reading from fails immediately
+// instead of hanging the launch; any failure disables events.
+func (w *wrapper) openEventPipe() *eventPipe {
+	path := w.env.get("BIOVAULT_EVENT_PIPE")
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		w.debugf("event pipe disabled: %v", err)
+		return nil
+	}
+	return &eventPipe{file: f, enc: json.NewEncoder(f)}
+}
+
+func (p *eventPipe) emit(event any) {
+	if p == nil || p.enc == nil {
+		return
+	}
+	if err := p.enc.Encode(event); err != nil {
+		p.enc = nil
+	}
+}
+
+func (p *eventPipe) Close() error {
+	if p == nil {
+		return nil
+	}
+	return p.file.Close()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// execTraceFlag asks for the child invocation to be saved as a script before
+// Nextflow is launched as usual: --wrapper-exec-trace=<path>.
+const execTraceFlag = "--wrapper-exec-trace="
+
+// writeExecTrace saves cmd as a script that replays it: a .cmd batch file on
+// Windows, a POSIX sh script elsewhere. It sets every variable of the child's
+// environment, with sensitive values scrubbed to ***, changes to the working
+// directory and runs java with the exact arguments.
+func (w *wrapper) writeExecTrace(path string, cmd *exec.Cmd) error {
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return os.WriteFile(path, []byte(w.execTrace(runtime.GOOS, cmd, dir)), 0o755)
+}
+
+func (w *wrapper) execTrace(goos string, cmd *exec.Cmd, dir string) string {
+	var b strings.Builder
+	if goos == "windows" {
+		// Everything is caret-escaped rather than quoted, as a value holding
+		// a double quote would end a set "K=V" early. A batch file cannot set
+		// a value spanning lines, so such variables are only noted.
+		b.WriteString("@echo off\r\n")
+		b.WriteString("rem biovault-wrapper exec trace; values of sensitive variables are replaced by ***\r\n")
+		b.WriteString("setlocal\r\n")
+		for _, kv := range cmd.Env {
+			if strings.HasPrefix(kv, "=") {
+				continue
+			}
+			kv = w.scrub(kv)
+			if strings.ContainsAny(kv, "\r\n") {
+				key, _, _ := strings.Cut(kv, "=")
+				fmt.Fprintf(&b, "rem %s is not set: its value spans several lines\r\n", cmdEscape(key, true))
+				continue
+			}
+			fmt.Fprintf(&b, "set %s\r\n", cmdEscape(kv, true))
+		}
+		fmt.Fprintf(&b, "cd /d %s\r\n", cmdPath(dir, true))
+		fmt.Fprintf(&b, "%s\r\n", cmdCommand(cmd.Path, cmd.Args[1:], true))
+	} else {
+		b.WriteString("#!/bin/sh\n")
+		b.WriteString("# biovault-wrapper exec trace; values of sensitive variables are replaced by ***\n")
+		for _, kv := range cmd.Env {
+			key, value, ok := strings.Cut(w.scrub(kv), "=")
+			if !ok || !configKeyPattern.MatchString(key) {
+				continue
+			}
+			fmt.Fprintf(&b, "export %s=%s\n", key, shellQuote(value))
+		}
+		fmt.Fprintf(&b, "cd %s || exit 1\n", shellQuote(dir))
+		fmt.Fprintf(&b, "exec %s\n", formatCommand(goos, cmd.Path, cmd.Args[1:]))
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecTraceBatch(t *testing.T) {
+	cmd := &exec.Cmd{
+		Path: `C:\bv\java\windows-x86_64\bin\java.exe`,
+		Args: []string{"java", "-jar", `C:\bv\nextflow.jar`, "run", "a&b", `say "hi"`},
+		Env: []string{
+			"=C:=C:\\bv",
+			"AMP=x&y|z",
+			`QUOTE=say "hi" (100%)`,
+			"MULTI=one\ntwo",
+			"TOKEN=secret",
+		},
+	}
+	w := &wrapper{redact: []string{"TOKEN"}}
+	got := w.execTrace("windows", cmd, `C:\Program Files (x86)\work`)
+
+	want := strings.Join([]string{
+		"@echo off",
+		"rem biovault-wrapper exec trace; values of sensitive variables are replaced by ***",
+		"setlocal",
+		"set AMP=x^&y^|z",
+		`set QUOTE=say ^"hi^" ^(100%%^)`,
+		"rem MULTI is not set: its value spans several lines",
+		"set TOKEN=***",
+		`cd /d "C:\Program Files (x86)\work"`,
+		`C:\bv\java\windows-x86_64\bin\java.exe -jar C:\bv\nextflow.jar run a^&b ^"say \^"hi\^"^"`,
+		"",
+	}, "\r\n")
+	if got != want {
+		t.Errorf("batch trace:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestRunExecTraceWithDryRun checks that the wrapper's own flags are accepted
+// in either order and that a dry run still writes the trace.
+func TestRunExecTraceWithDryRun(t *testing.T) {
+	fakeInstall(t)
+	for _, order := range [][]string{
+		{"--wrapper-dry-run", "--wrapper-exec-trace="},
+		{"--wrapper-exec-trace=", "--wrapper-dry-run"},
+	} {
+		trace := filepath.Join(t.TempDir(), "trace")
+		args := append([]string(nil), order...)
+		for i, arg := range args {
+			if arg == execTraceFlag {
+				args[i] += trace
+			}
+		}
+
+		var stdout, stderr bytes.Buffer
+		if code := run(append(args, "run", "hello"), testEnv(), &stdout, &stderr, nil); code != 0 {
+			t.Fatalf("run(%q) returned %d, stderr:\n%s", args, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "run hello") {
+			t.Errorf("run(%q) printed no dry run:\n%s", args, stdout.String())
+		}
+		data, err := os.ReadFile(trace)
+		if err != nil {
+			t.Fatalf("run(%q) wrote no trace: %v", args, err)
+		}
+		if !strings.Contains(string(data), "run hello") {
+			t.Errorf("trace for run(%q) lacks the command:\n%s", args, data)
+		}
+	}
+}
+
+func TestRunRejectsEmptyExecTrace(t *testing.T) {
+	fakeInstall(t)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--wrapper-exec-trace=", "run"}, testEnv(), &stdout, &stderr, nil); code != exitBadConfig {
+		t.Errorf("run returned %d, want %d; stderr:\n%s", code, exitBadConfig, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("nextflow was launched: %s", stdout.String())
+	}
+}
@@ -0,0 +1,3 @@
+module nextflow-wrapper
+
+go 1.21
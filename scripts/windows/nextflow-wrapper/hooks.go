@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// runPrelaunch runs BIOVAULT_PRELAUNCH_CMD through the platform shell with the
+// same environment and working directory Nextflow will get, killing it and
+// everything it started when ctx, the launch timeout, expires. A non-zero
+// result is the code the wrapper should exit with instead of launching
+// Nextflow.
+func (w *wrapper) runPrelaunch(ctx context.Context, env environ, dir string) int {
+	script := w.env.get("BIOVAULT_PRELAUNCH_CMD")
+	if script == "" {
+		return 0
+	}
+
+	cmd := shellCommand(ctx, env, script)
+	cmd.Stdout = w.stdout
+	cmd.Stderr = w.stderr
+	cmd.Stdin = childStdin(w.stdin)
+	cmd.Env = env
+	cmd.Dir = dir
+	configureChild(cmd)
+	cmd.Cancel = func() error { return killChild(cmd.Process) }
+	cmd.WaitDelay = childWaitDelay
+	w.debugf("prelaunch: %s", script)
+
+	err := cmd.Start()
+	if err == nil {
+		if !w.env.enabled("BIOVAULT_NO_JOB_OBJECT") {
+			if err := assignJob(cmd.Process); err != nil {
+				w.logf("warning: failed to tie BIOVAULT_PRELAUNCH_CMD's processes to the wrapper: %v", err)
+			}
+		}
+		err = cmd.Wait()
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		w.logf("BIOVAULT_PRELAUNCH_CMD did not finish within BIOVAULT_WRAPPER_TIMEOUT and was killed; not starting nextflow")
+		return exitTimeout
+	case context.Canceled:
+		// An interrupt killed the hook; run reports it.
+		return exitWrapperError
+	}
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		w.logf("BIOVAULT_PRELAUNCH_CMD exited with code %d; not starting nextflow", exitErr.ExitCode())
+		return exitErr.ExitCode()
+	}
+	w.logf("BIOVAULT_PRELAUNCH_CMD failed; not starting nextflow: %v", err)
+	return exitWrapperError
+}
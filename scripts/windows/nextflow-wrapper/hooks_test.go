@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPrelaunch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts are written for sh")
+	}
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		want    int
+		log     string
+	}{
+		{"unset", "", 0, 0, ""},
+		{"success", "exit 0", 0, 0, ""},
+		{"failure", "exit 3", 0, 3, "exited with code 3"},
+		// Not exec'd: the shell's own child must be killed along with it.
+		{"timeout", "sleep 30; echo done", 100 * time.Millisecond, exitTimeout, "did not finish within BIOVAULT_WRAPPER_TIMEOUT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			w := &wrapper{env: testEnv("BIOVAULT_PRELAUNCH_CMD=" + tt.script), stdout: &bytes.Buffer{}, stderr: &stderr}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			if got := w.runPrelaunch(ctx, w.env, t.TempDir()); got != tt.want {
+				t.Errorf("runPrelaunch = %d, want %d; stderr:\n%s", got, tt.want, stderr.String())
+			}
+			if tt.timeout > 0 && time.Since(start) >= childWaitDelay {
+				t.Errorf("the hook outlived the timeout by %s", time.Since(start)-tt.timeout)
+			}
+			if !strings.Contains(stderr.String(), tt.log) {
+				t.Errorf("stderr %q does not mention %q", stderr.String(), tt.log)
+			}
+		})
+	}
+}
+
+// TestRunPrelaunchTimeoutKillsTree checks that a timed-out hook leaves
+// nothing behind: the sleep the shell started must die with it.
+func TestRunPrelaunchTimeoutKillsTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts are written for sh")
+	}
+	var stdout bytes.Buffer
+	w := &wrapper{env: testEnv("BIOVAULT_PRELAUNCH_CMD=sleep 30 & echo $!; wait; echo done"), stdout: &stdout, stderr: &bytes.Buffer{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if got := w.runPrelaunch(ctx, w.env, t.TempDir()); got != exitTimeout {
+		t.Fatalf("runPrelaunch = %d, want %d", got, exitTimeout)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatalf("no pid from the hook: %q", stdout.String())
+	}
+	// A killed orphan lingers briefly as a zombie until init reaps it.
+	for deadline := time.Now().Add(5 * time.Second); processAlive(pid); {
+		if time.Now().After(deadline) {
+			if proc, err := os.FindProcess(pid); err == nil {
+				proc.Kill()
+			}
+			t.Fatalf("sleep %d outlived the timed-out hook", pid)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// verifyJarChecksum compares jar against the digest in the <jar>.sha256
+// sidecar, if there is one. The sidecar may contain a bare hex digest or a
+// sha256sum-style "digest  filename" line.
+func verifyJarChecksum(jar string) error {
+	sidecar := jar + ".sha256"
+	data, err := os.ReadFile(sidecar)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", sidecar, err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum file is empty: %s", sidecar)
+	}
+	want := strings.ToLower(fields[0])
+
+	f, err := os.Open(jar)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to read %s: %w", jar, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("nextflow.jar checksum mismatch for %s (expected %s, got %s); the file is likely corrupt or partially downloaded, please reinstall biovault-desktop", jar, want, got)
+	}
+	return nil
+}
+
+// verifyJarArchive checks that jar is a readable zip with a manifest, which
+// catches truncated downloads before the JVM reports "Invalid or corrupt
+// jarfile". Only the central directory is read, not the entries themselves.
+func verifyJarArchive(jar string) error {
+	r, err := zip.OpenReader(jar)
+	if err != nil {
+		return fmt.Errorf("nextflow.jar is corrupt: %s is not a valid jar (%v); please reinstall biovault-desktop", jar, err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name == "META-INF/MANIFEST.MF" {
+			return nil
+		}
+	}
+	return fmt.Errorf("nextflow.jar is corrupt: %s has no META-INF/MANIFEST.MF; please reinstall biovault-desktop", jar)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMinJava   = 17
+	javaProbeTimeout = 30 * time.Second
+)
+
+// resolveJava picks the java binary to launch, in order of precedence:
+//
+//  1. BIOVAULT_BUNDLED_JAVA, if it names an existing file
+//  2. <dir>/bin/<java> for each dir in BIOVAULT_JAVA_SEARCH_PATHS, in order
+//  3. the bundled runtime at ../../java/<platform>/bin/<java> relative to exeDir,
+//     preferring ../../java/<platform>-jdk<N> when jar has a java.requirement
+//  4. $JAVA_HOME/bin/<java>
+//  5. bare "java", resolved from PATH at launch
+//
+// <platform> and <java> come from javaPlatforms and javaBinary for the OS and
+// architecture the wrapper was built for. BIOVAULT_DISABLE_BUNDLED_JAVA=1
+// skips steps 1 to 3 so an admin can force the system Java. Candidates that
+// exist but fail a java -version probe are skipped.
+//
+// bundled reports whether the result is one of the runtimes we ship, whose
+// version is known and need not be checked.
+func (w *wrapper) resolveJava(exeDir, jar string) (java string, bundled bool) {
+	bin := javaBinary(runtime.GOOS)
+
+	var blocked []string
+	runnable := func(path string) (string, bool) {
+		if !w.locate(path) {
+			return path, false
+		}
+		if _, err := w.probeJava(path); err != nil {
+			w.debugf("java %s exists but cannot be run: %v", path, err)
+			blocked = append(blocked, path)
+			w.blockedJava = true
+			if brokenRuntimeError(err) {
+				w.brokenJava = true
+			}
+			return path, false
+		}
+		return path, true
+	}
+
+	if !w.env.enabled("BIOVAULT_DISABLE_BUNDLED_JAVA") {
+		if env := w.env.get("BIOVAULT_BUNDLED_JAVA"); env != "" {
+			if java, ok := runnable(env); ok {
+				return java, true
+			}
+		}
+
+		for _, dir := range filepath.SplitList(w.env.get("BIOVAULT_JAVA_SEARCH_PATHS")) {
+			if dir == "" {
+				continue
+			}
+			if java, ok := runnable(filepath.Join(dir, "bin", bin)); ok {
+				return java, false
+			}
+		}
+
+		platforms := javaPlatforms(runtime.GOOS, runtime.GOARCH)
+		required, hasRequirement := w.javaRequirement(jar)
+		if hasRequirement {
+			for _, platform := range platforms {
+				dir := fmt.Sprintf("%s-jdk%d", platform, required)
+				rel := filepath.Clean(filepath.Join(exeDir, "..", "..", "java", dir, "bin", bin))
+				if java, ok := runnable(rel); ok {
+					w.debugf("bundled java: using %s runtime for java.requirement %d", dir, required)
+					return java, true
+				}
+			}
+		}
+		for _, platform := range platforms {
+			rel := filepath.Clean(filepath.Join(exeDir, "..", "..", "java", platform, "bin", bin))
+			if java, ok := runnable(rel); ok {
+				w.debugf("bundled java: using %s runtime", platform)
+				if hasRequirement {
+					w.checkRequirement(java, jar, required)
+				}
+				return java, true
+			}
+		}
+	}
+
+	if home := w.env.get("JAVA_HOME"); home != "" {
+		if java, ok := runnable(filepath.Join(home, "bin", bin)); ok {
+			return java, false
+		}
+	}
+
+	if len(blocked) > 0 {
+		w.warnBlockedJava(blocked)
+	}
+	return "java", false
+}
+
+// checkRequirement warns when the default bundled runtime java, used for
+// lack of a <platform>-jdk<N> one, is not the Java major version jar requires.
+func (w *wrapper) checkRequirement(java, jar string, required int) {
+	output, _ := w.probeJava(java)
+	if major, ok := parseJavaMajor(output); ok && major == required {
+		return
+	}
+	w.logf("warning: %s requires Java %d but no matching runtime is bundled; using the default bundled runtime", filepath.Base(jar), required)
+}
+
+// javaRequirement reads the Java major version a jar needs from its sidecar:
+// <jar>.java.requirement, or java.requirement in the jar's directory, holding
+// a number such as 21. It reports false when there is no sidecar; an
+// unreadable one is warned about and ignored.
+func (w *wrapper) javaRequirement(jar string) (int, bool) {
+	if jar == "" {
+		return 0, false
+	}
+	for _, path := range []string{jar + ".java.requirement", filepath.Join(filepath.Dir(jar), "java.requirement")} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		major, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || major < 1 {
+			w.logf("warning: ignoring %s: expected a Java major version such as 21", path)
+			return 0, false
+		}
+		return major, true
+	}
+	return 0, false
+}
+
+func (w *wrapper) warnBlockedJava(blocked []string) {
+	if w.warnedBlockedJava {
+		return
+	}
+	w.warnedBlockedJava = true
+	w.logf("warning: java was found but could not be run: %s", strings.Join(blocked, ", "))
+	switch {
+	case w.brokenJava:
+		w.logf(incompleteRuntimeMessage)
+	case runtime.GOOS == "windows":
+		w.logf("This usually means Windows blocked files extracted from a downloaded archive. Unblock them (file Properties > Unblock) or reinstall biovault-desktop.")
+	default:
+		w.logf("Check that the file is executable, or reinstall biovault-desktop.")
+	}
+}
+
+// javaVersionPattern matches both `openjdk version "17.0.2"` and the legacy
+// `java version "1.8.0_391"` banners printed by java -version.
+var javaVersionPattern = regexp.MustCompile(`version "(\d+)(?:\.(\d+))?`)
+
+func parseJavaMajor(output string) (int, bool) {
+	m := javaVersionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return 0, false
+	}
+	major, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	if major == 1 && m[2] != "" {
+		major, err = strconv.Atoi(m[2])
+		if err != nil {
+			return 0, false
+		}
+	}
+	return major, true
+}
+
+func (w *wrapper) minJava() (int, error) {
+	value := w.env.get("BIOVAULT_MIN_JAVA")
+	if value == "" {
+		return defaultMinJava, nil
+	}
+	min, err := strconv.Atoi(value)
+	if err != nil || min < 1 {
+		return 0, fmt.Errorf("invalid BIOVAULT_MIN_JAVA %q: expected a Java major version such as 17", value)
+	}
+	return min, nil
+}
+
+type javaProbe struct {
+	output string
+	err    error
+}
+
+// probeJava runs java -version and returns its combined output. Results are
+// remembered so resolution and the version check share a single probe, and
+// successful ones are also kept in the on-disk version cache across launches.
+func (w *wrapper) probeJava(java string) (string, error) {
+	if p, ok := w.probes[java]; ok {
+		return p.output, p.err
+	}
+	if w.probes == nil {
+		w.probes = make(map[string]javaProbe)
+	}
+	if out, ok := w.cachedProbe(java); ok {
+		w.probes[java] = javaProbe{output: out}
+		return out, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), javaProbeTimeout)
+	defer cancel()
+	cmd := execCommand(ctx, java, "-version")
+	cmd.Env = w.env
+	out, err := cmd.CombinedOutput()
+
+	w.probes[java] = javaProbe{string(out), err}
+	if err == nil {
+		w.storeProbe(java, string(out))
+	}
+	return string(out), err
+}
+
+// javaMajor reports the major version java -version prints. It returns false
+// if java cannot be run or its output is not recognised; in that case the
+// launch itself will surface the problem.
+func (w *wrapper) javaMajor(java string) (int, bool) {
+	out, err := w.probeJava(java)
+	if err != nil {
+		return 0, false
+	}
+	return parseJavaMajor(out)
+}
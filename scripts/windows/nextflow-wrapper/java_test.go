@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestResolveJavaRequirement checks the java.requirement sidecar against the
+// default bundled runtime, which the test helper reports as Java 21.
+func TestResolveJavaRequirement(t *testing.T) {
+	tests := []struct {
+		name     string
+		required string
+		jdk      bool
+		warn     bool
+	}{
+		{"default runtime matches", "21", false, false},
+		{"default runtime too old", "25", false, true},
+		{"matching runtime bundled", "17", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exeDir, java, jar := fakeInstall(t)
+			if err := os.WriteFile(jar+".java.requirement", []byte(tt.required+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			want := java
+			if tt.jdk {
+				platform := javaPlatform(runtime.GOOS, runtime.GOARCH) + "-jdk" + tt.required
+				want = filepath.Join(filepath.Dir(filepath.Dir(exeDir)), "java", platform, "bin", javaBinary(runtime.GOOS))
+				if err := os.MkdirAll(filepath.Dir(want), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(want, nil, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var stderr bytes.Buffer
+			w := &wrapper{env: testEnv(), stderr: &stderr}
+			if got, _ := w.resolveJava(exeDir, jar); got != want {
+				t.Errorf("resolveJava = %s, want %s", got, want)
+			}
+			if warned := strings.Contains(stderr.String(), "requires Java"); warned != tt.warn {
+				t.Errorf("warned = %v, want %v; stderr:\n%s", warned, tt.warn, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	jobObjectExtendedLimitInformationClass = 9
+	jobObjectLimitKillOnJobClose           = 0x00002000
+
+	processSetQuota  = 0x0100
+	processTerminate = 0x0001
+)
+
+var (
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+)
+
+// jobs maps the pid of each child assigned by assignJob to its job handle.
+var jobs sync.Map
+
+// jobObjectExtendedLimitInformation mirrors JOBOBJECT_EXTENDED_LIMIT_INFORMATION.
+type jobObjectExtendedLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+	IoCounters              [6]uint64
+	ProcessMemoryLimit      uintptr
+	JobMemoryLimit          uintptr
+	PeakProcessMemoryUsed   uintptr
+	PeakJobMemoryUsed       uintptr
+}
+
+// assignJob puts the child in a job object that kills every process in it
+// when its last handle closes. The handle is deliberately never closed, so it
+// lives exactly as long as the wrapper: however the wrapper ends, including
+// from Task Manager, Windows closes it and takes down Nextflow and everything
+// it spawned. Processes the child starts before it is assigned, in the
+// moment after Start, are not covered.
+func assignJob(proc *os.Process) error {
+	job, _, err := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return err
+	}
+
+	var info jobObjectExtendedLimitInformation
+	info.LimitFlags = jobObjectLimitKillOnJobClose
+	if ok, _, err := procSetInformationJobObject.Call(job, jobObjectExtendedLimitInformationClass, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info)); ok == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return err
+	}
+
+	h, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(proc.Pid))
+	if err != nil {
+		syscall.CloseHandle(syscall.Handle(job))
+		return err
+	}
+	defer syscall.CloseHandle(h)
+	if ok, _, err := procAssignProcessToJobObject.Call(job, uintptr(h)); ok == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return err
+	}
+	jobs.Store(proc.Pid, job)
+	return nil
+}
+
+// terminateJob kills every process in the job assigned to pid, reporting
+// false if pid has no job or it could not be terminated.
+func terminateJob(pid int) bool {
+	job, ok := jobs.Load(pid)
+	if !ok {
+		return false
+	}
+	r, _, _ := procTerminateJobObject.Call(job.(uintptr), 1)
+	return r != 0
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var heapSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+
+// utf8Props are the system properties BIOVAULT_FORCE_UTF8 pins so Nextflow
+// behaves the same regardless of the machine's locale and code page.
+var utf8Props = []string{
+	"-Dfile.encoding=UTF-8",
+	"-Dsun.jnu.encoding=UTF-8",
+	"-Duser.language=en",
+	"-Duser.country=US",
+}
+
+// offlineProps are added by BIOVAULT_OFFLINE so that any network access left
+// in Nextflow fails fast instead of waiting on proxy auto-discovery or DNS.
+var offlineProps = []string{
+	"-Djava.net.useSystemProxies=false",
+	"-Dsun.net.client.defaultConnectTimeout=2000",
+	"-Dsun.net.client.defaultReadTimeout=2000",
+}
+
+// jvmArgs returns the JVM options that precede -jar. Heap sizes come first,
+// then NXF_OPTS (which Nextflow's own launcher honours), then system
+// properties from BIOVAULT_SYS_PROPS, then BIOVAULT_JVM_OPTS, so later
+// sources override earlier ones. BIOVAULT_FORCE_UTF8 and BIOVAULT_OFFLINE
+// properties are added last, except for any property already set by one of
+// those sources.
+func (w *wrapper) jvmArgs() ([]string, error) {
+	var args []string
+	for _, heap := range []struct{ env, flag string }{
+		{"BIOVAULT_JAVA_XMX", "-Xmx"},
+		{"BIOVAULT_JAVA_XMS", "-Xms"},
+	} {
+		value := w.env.get(heap.env)
+		if value == "" {
+			continue
+		}
+		if !heapSizePattern.MatchString(value) {
+			return nil, fmt.Errorf("invalid %s %q: expected a size such as 512m or 4g", heap.env, value)
+		}
+		args = append(args, heap.flag+value)
+	}
+
+	args = append(args, strings.Fields(w.env.get("NXF_OPTS"))...)
+
+	if path := w.env.get("BIOVAULT_SYS_PROPS"); path != "" {
+		props, err := readSysProps(path)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, props...)
+	}
+
+	opts, err := splitArgs(w.env.get("BIOVAULT_JVM_OPTS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BIOVAULT_JVM_OPTS: %w", err)
+	}
+	for _, opt := range opts {
+		if !strings.HasPrefix(opt, "-") {
+			return nil, fmt.Errorf("invalid BIOVAULT_JVM_OPTS: %q is not a JVM option (must start with -)", opt)
+		}
+	}
+	args = append(args, opts...)
+
+	if w.env.enabled("BIOVAULT_FORCE_UTF8") {
+		args = withProps(args, utf8Props)
+	}
+	if w.env.enabled("BIOVAULT_OFFLINE") {
+		args = withProps(args, offlineProps)
+	}
+	return args, nil
+}
+
+// withProps appends each -Dname=value in props that args does not already
+// set.
+func withProps(args, props []string) []string {
+	for _, prop := range props {
+		key, _, _ := strings.Cut(prop, "=")
+		if !hasProp(args, key) {
+			args = append(args, prop)
+		}
+	}
+	return args
+}
+
+// hasProp reports whether args already sets the system property key, given
+// as -Dname.
+func hasProp(args []string, key string) bool {
+	for _, arg := range args {
+		if arg == key || strings.HasPrefix(arg, key+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// readSysProps reads a BIOVAULT_SYS_PROPS file of key=value lines and returns
+// one -Dkey=value flag per line. Blank lines and lines starting with # are
+// skipped; whitespace around the key and value is trimmed.
+func readSysProps(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BIOVAULT_SYS_PROPS file: %w", err)
+	}
+	var flags []string
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid BIOVAULT_SYS_PROPS %s: line %d: expected key=value, got %q", path, i+1, line)
+		}
+		flags = append(flags, "-D"+key+"="+strings.TrimSpace(value))
+	}
+	return flags, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"os/signal"
+	"time"
+)
+
+const retryBaseDelay = 500 * time.Millisecond
+
+// childWaitDelay bounds how long Wait keeps copying output after nextflow
+// exits, or after it is killed, when a grandchild it left behind still holds
+// the output pipes open.
+const childWaitDelay = 5 * time.Second
+
+// startError marks a failure to start the child at all, as opposed to the
+// child exiting with an error.
+type startError struct{ err error }
+
+func (e *startError) Error() string { return e.err.Error() }
+func (e *startError) Unwrap() error { return e.err }
+
+// execute runs cmd to completion, relaying the interrupts run intercepts to
+// it. It returns the child's exit code, or an error if the child could not be
+// run at all.
+func (w *wrapper) execute(cmd *exec.Cmd) (int, error) {
+	sigs := w.signals
+	if sigs == nil {
+		sigs = interceptSignals()
+		defer signal.Stop(sigs)
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, &startError{err}
+	}
+	w.events.emit(startedEvent{Event: "started", PID: cmd.Process.Pid})
+	if !w.env.enabled("BIOVAULT_NO_JOB_OBJECT") {
+		if err := assignJob(cmd.Process); err != nil {
+			w.logf("warning: failed to tie nextflow's processes to the wrapper: %v", err)
+		}
+	}
+	if err := applyPriority(cmd.Process, w.prio); err != nil {
+		w.logf("warning: failed to lower nextflow's priority: %v", err)
+	}
+	stop := forwardSignals(sigs, cmd.Process)
+	err := cmd.Wait()
+	stop()
+
+	if errors.Is(err, exec.ErrWaitDelay) {
+		w.debugf("nextflow exited but its output was still held open after %s; stopped copying it", childWaitDelay)
+		return cmd.ProcessState.ExitCode(), nil
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
+
+// executeWithRetry runs a fresh command from newCmd, retrying up to retries
+// times with exponential backoff when the process fails to start, which
+// happens transiently under aggressive antivirus scanning. A child that ran
+// and exited is never retried, whatever its exit code.
+func (w *wrapper) executeWithRetry(ctx context.Context, newCmd func() *exec.Cmd, retries int) (int, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		code, err := w.execute(newCmd())
+
+		var startErr *startError
+		if !errors.As(err, &startErr) || errors.Is(err, exec.ErrNotFound) || attempt > retries || ctx.Err() != nil {
+			return code, err
+		}
+		w.logf("failed to start java (attempt %d of %d): %v; retrying in %s", attempt, retries+1, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// roundDuration trims d to a readable precision: milliseconds for short runs,
+// whole seconds otherwise.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+const incompleteRuntimeMessage = "The bundled Java runtime appears incomplete; please reinstall biovault-desktop."
+
+func brokenRuntimeError(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && brokenRuntimeExit(exitErr.ExitCode())
+}
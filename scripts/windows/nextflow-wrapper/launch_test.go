@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestExecuteStopsWaitingForHeldOutput checks that a grandchild left holding
+// the output pipe does not keep the wrapper waiting once nextflow has exited.
+func TestExecuteStopsWaitingForHeldOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", "sleep 30 & exit 0")
+	cmd.Stdout = &out
+	configureChild(cmd)
+	cmd.WaitDelay = 100 * time.Millisecond
+	t.Cleanup(func() { killChild(cmd.Process) })
+
+	w := &wrapper{env: testEnv(), stderr: &bytes.Buffer{}}
+	start := time.Now()
+	code, err := w.execute(cmd)
+	if err != nil || code != 0 {
+		t.Fatalf("execute = %d, %v; want 0, nil", code, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("execute waited %s for the orphaned grandchild", elapsed)
+	}
+}
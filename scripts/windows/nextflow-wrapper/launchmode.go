@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// launchMode builds the java arguments that select what to run, between the
+// JVM options and Nextflow's own arguments.
+type launchMode func(w *wrapper, jar string) ([]string, error)
+
+// launchModes are the values accepted by BIOVAULT_JAVA_LAUNCH_MODE.
+var launchModes = map[string]launchMode{
+	"jar":    jarLaunch,
+	"module": moduleLaunch,
+}
+
+// launchArgs picks the launch mode: BIOVAULT_JAVA_LAUNCH_MODE if set,
+// otherwise module mode when the jar has a <jar>.module sidecar, otherwise
+// the classic -jar.
+func (w *wrapper) launchArgs(jar string) ([]string, error) {
+	name := strings.ToLower(w.env.get("BIOVAULT_JAVA_LAUNCH_MODE"))
+	if name == "" {
+		name = "jar"
+		if existingFile(jar + ".module") {
+			name = "module"
+		}
+	}
+	mode, ok := launchModes[name]
+	if !ok {
+		names := make([]string, 0, len(launchModes))
+		for n := range launchModes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("invalid BIOVAULT_JAVA_LAUNCH_MODE %q: expected one of %s", name, strings.Join(names, ", "))
+	}
+	w.debugf("launch mode: %s", name)
+	return mode(w, jar)
+}
+
+func jarLaunch(w *wrapper, jar string) ([]string, error) {
+	return []string{"-jar", jar}, nil
+}
+
+// moduleLaunch runs Nextflow as a named module: --module-path <dir> -m
+// <module>[/<main class>]. The module comes from BIOVAULT_JAVA_MODULE or the
+// first line of the <jar>.module sidecar; the module path defaults to the
+// jar's directory and can be set with BIOVAULT_JAVA_MODULE_PATH.
+func moduleLaunch(w *wrapper, jar string) ([]string, error) {
+	module := w.env.get("BIOVAULT_JAVA_MODULE")
+	if module == "" {
+		data, err := os.ReadFile(jar + ".module")
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s.module: %w", jar, err)
+		}
+		module, _, _ = strings.Cut(strings.TrimSpace(string(data)), "\n")
+		module = strings.TrimSpace(module)
+	}
+	if module == "" {
+		return nil, errors.New("module launch mode needs a module name: set BIOVAULT_JAVA_MODULE or add a " + filepath.Base(jar) + ".module sidecar")
+	}
+
+	path := w.env.get("BIOVAULT_JAVA_MODULE_PATH")
+	if path == "" {
+		path = filepath.Dir(jar)
+	}
+	return []string{"--module-path", path, "-m", module}, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLaunchArgs(t *testing.T) {
+	dir := t.TempDir()
+	jar := filepath.Join(dir, "nextflow.jar")
+	tests := []struct {
+		name    string
+		env     []string
+		sidecar string // contents of nextflow.jar.module; "" for none
+		want    []string
+		err     string
+	}{
+		{name: "default", want: []string{"-jar", jar}},
+		{name: "explicit jar", env: []string{"BIOVAULT_JAVA_LAUNCH_MODE=jar"}, sidecar: "nextflow\n", want: []string{"-jar", jar}},
+		{name: "module from env", env: []string{"BIOVAULT_JAVA_LAUNCH_MODE=module", "BIOVAULT_JAVA_MODULE=nextflow/nextflow.cli.Launcher"}, want: []string{"--module-path", dir, "-m", "nextflow/nextflow.cli.Launcher"}},
+		{name: "mode is case-insensitive", env: []string{"BIOVAULT_JAVA_LAUNCH_MODE=Module", "BIOVAULT_JAVA_MODULE=nextflow"}, want: []string{"--module-path", dir, "-m", "nextflow"}},
+		{name: "sidecar selects module", sidecar: "  nextflow  \nignored\n", want: []string{"--module-path", dir, "-m", "nextflow"}},
+		{name: "env overrides sidecar module", env: []string{"BIOVAULT_JAVA_MODULE=other"}, sidecar: "nextflow\n", want: []string{"--module-path", dir, "-m", "other"}},
+		{name: "module path override", env: []string{"BIOVAULT_JAVA_MODULE_PATH=/opt/mods"}, sidecar: "nextflow\n", want: []string{"--module-path", "/opt/mods", "-m", "nextflow"}},
+		{name: "missing module", env: []string{"BIOVAULT_JAVA_LAUNCH_MODE=module"}, err: "set BIOVAULT_JAVA_MODULE or add a nextflow.jar.module sidecar"},
+		{name: "empty sidecar", sidecar: "\n", err: "needs a module name"},
+		{name: "unknown mode", env: []string{"BIOVAULT_JAVA_LAUNCH_MODE=classpath"}, err: `invalid BIOVAULT_JAVA_LAUNCH_MODE "classpath": expected one of jar, module`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(jar + ".module")
+			if tt.sidecar != "" {
+				if err := os.WriteFile(jar+".module", []byte(tt.sidecar), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := &wrapper{env: testEnv(tt.env...), stderr: &bytes.Buffer{}}
+			got, err := w.launchArgs(jar)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("launchArgs error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("launchArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunModuleLaunch(t *testing.T) {
+	exeDir, java, jar := fakeInstall(t)
+	if err := os.WriteFile(jar+".module", []byte("nextflow\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"run", "hello"}, testEnv(), &stdout, &stderr, nil); code != 0 {
+		t.Fatalf("run returned %d, stderr:\n%s", code, stderr.String())
+	}
+	var got []string
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected child output %q: %v", stdout.String(), err)
+	}
+	want := []string{java, "--module-path", exeDir, "-m", "nextflow", "run", "hello"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("launched %q, want %q", got, want)
+	}
+}
+
+func TestRunModuleLaunchWithoutModule(t *testing.T) {
+	fakeInstall(t)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"run"}, testEnv("BIOVAULT_JAVA_LAUNCH_MODE=module"), &stdout, &stderr, nil)
+	if code != exitBadConfig {
+		t.Errorf("run returned %d, want %d (stderr: %s)", code, exitBadConfig, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("nextflow was launched: %s", stdout.String())
+	}
+}
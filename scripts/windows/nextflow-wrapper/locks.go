@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cleanStaleLocks implements BIOVAULT_DANGLING_LOCK_CLEANUP. It looks in dir
+// for .nextflow.pid and for lock files under .nextflow, and removes only those
+// that record a PID which is no longer running. Lock files without a PID are
+// left alone, since their owner cannot be told apart from a live run.
+func (w *wrapper) cleanStaleLocks(dir string) {
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return
+		}
+	}
+
+	locks := []string{filepath.Join(dir, ".nextflow.pid")}
+	filepath.WalkDir(filepath.Join(dir, ".nextflow"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && (d.Name() == "LOCK" || strings.HasSuffix(d.Name(), ".lock")) {
+			locks = append(locks, path)
+		}
+		return nil
+	})
+
+	for _, lock := range locks {
+		pid, ok := lockOwner(lock)
+		switch {
+		case !ok:
+			continue
+		case processAlive(pid):
+			w.debugf("lock %s is held by running pid %d", lock, pid)
+		default:
+			if err := os.Remove(lock); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				w.logf("warning: failed to remove stale lock %s: %v", lock, err)
+				continue
+			}
+			w.logf("warning: removed stale lock %s (pid %d is not running)", lock, pid)
+		}
+	}
+}
+
+// lockOwner reads the PID recorded in a lock file. It reports false if the
+// file is missing or holds anything other than a single positive integer.
+func lockOwner(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) > 64 {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	logFileName = "wrapper.log"
+	logMaxSize  = 5 << 20
+)
+
+// invocation is what logInvocation records, filled in as run resolves it.
+type invocation struct {
+	java  string
+	args  []string
+	start time.Time
+}
+
+// logInvocation appends one line per launch attempt to wrapper.log in
+// BIOVAULT_WRAPPER_LOG_DIR, rotating to wrapper.log.1 once it passes
+// logMaxSize. Attempts that exit before java starts are recorded too, with
+// whatever had been resolved; the duration is the wrapper's whole run. It is
+// best-effort: any failure just skips the entry.
+func (w *wrapper) logInvocation(inv invocation, code int) {
+	dir := w.env.get("BIOVAULT_WRAPPER_LOG_DIR")
+	if dir == "" {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+
+	path := filepath.Join(dir, logFileName)
+	if info, err := os.Stat(path); err == nil && info.Size() >= logMaxSize {
+		_ = os.Rename(path, path+".1")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	java := inv.java
+	if java == "" {
+		java = "-"
+	}
+	fmt.Fprintf(f, "%s java=%s args=%q exit=%d duration=%s\n",
+		time.Now().Format(time.RFC3339), java, inv.args, code, time.Since(inv.start).Round(time.Millisecond))
+}
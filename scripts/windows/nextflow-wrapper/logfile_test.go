@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunLogsEveryExit checks that launches which fail before java starts are
+// recorded in wrapper.log as well as ones that run.
+func TestRunLogsEveryExit(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    []string
+		noJar  bool
+		code   int
+		fields []string
+	}{
+		{name: "launched", code: 0, fields: []string{`"-jar"`, `"hello"`}},
+		{name: "missing jar", noJar: true, code: exitJarNotFound, fields: []string{"java=-", `args=["hello"]`}},
+		{name: "bad config", env: []string{"BIOVAULT_WRAPPER_TIMEOUT=soon"}, code: exitBadConfig, fields: []string{`"hello"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, java, jar := fakeInstall(t)
+			if tt.noJar {
+				if err := os.Remove(jar); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := filepath.Join(t.TempDir(), "logs")
+
+			var stderr bytes.Buffer
+			env := testEnv(append(tt.env, "BIOVAULT_WRAPPER_LOG_DIR="+dir)...)
+			if code := run([]string{"hello"}, env, &bytes.Buffer{}, &stderr, nil); code != tt.code {
+				t.Fatalf("run returned %d, want %d; stderr:\n%s", code, tt.code, stderr.String())
+			}
+			data, err := os.ReadFile(filepath.Join(dir, logFileName))
+			if err != nil {
+				t.Fatalf("no wrapper.log entry: %v", err)
+			}
+			line := string(data)
+			if tt.code == 0 && !strings.Contains(line, "java="+java+" ") {
+				t.Errorf("wrapper.log entry %q does not name %s", line, java)
+			}
+			for _, field := range append(tt.fields, fmt.Sprintf("exit=%d", tt.code)) {
+				if !strings.Contains(line, field) {
+					t.Errorf("wrapper.log entry %q lacks %s", line, field)
+				}
+			}
+		})
+	}
+}
+
+func TestRunDoesNotLogDryRun(t *testing.T) {
+	fakeInstall(t)
+	dir := filepath.Join(t.TempDir(), "logs")
+	env := testEnv("BIOVAULT_WRAPPER_LOG_DIR=" + dir)
+	if code := run([]string{"--wrapper-dry-run", "run"}, env, &bytes.Buffer{}, &bytes.Buffer{}, nil); code != 0 {
+		t.Fatalf("run returned %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, logFileName)); !os.IsNotExist(err) {
+		t.Errorf("a dry run was logged: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "runtime"
+
+// maxPath is the legacy Windows MAX_PATH limit.
+const maxPath = 260
+
+// locate reports whether path is an existing file. Go reads long paths fine,
+// but java.exe and CreateProcess may not, so on Windows a path at or beyond
+// MAX_PATH draws a one-time warning to install somewhere shorter.
+func (w *wrapper) locate(path string) bool {
+	if longPath(runtime.GOOS, path) {
+		w.warnLongPath(path)
+	}
+	return w.candidate(path)
+}
+
+func longPath(goos, path string) bool {
+	return goos == "windows" && len(path) >= maxPath
+}
+
+func (w *wrapper) warnLongPath(path string) {
+	if w.warnedLongPath {
+		return
+	}
+	w.warnedLongPath = true
+	w.logf("warning: path is %d characters, beyond the Windows MAX_PATH limit, and java may fail to start; install biovault-desktop to a shorter location: %s", len(path), path)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLongPath(t *testing.T) {
+	tests := []struct {
+		goos string
+		n    int
+		want bool
+	}{
+		{"windows", maxPath - 1, false},
+		{"windows", maxPath, true},
+		{"windows", 2 * maxPath, true},
+		{"linux", 2 * maxPath, false},
+		{"darwin", 2 * maxPath, false},
+	}
+	for _, tt := range tests {
+		path := `C:\` + strings.Repeat("a", tt.n-3)
+		if got := longPath(tt.goos, path); got != tt.want {
+			t.Errorf("longPath(%s, %d chars) = %v, want %v", tt.goos, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestLocateLongPath builds a real jar beyond MAX_PATH: it must still be
+// found, and on Windows draw exactly one warning however often it is located.
+func TestLocateLongPath(t *testing.T) {
+	dir := t.TempDir()
+	for len(dir) < maxPath {
+		dir = filepath.Join(dir, strings.Repeat("d", 40))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	jar := filepath.Join(dir, "nextflow.jar")
+	if err := os.WriteFile(jar, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	w := &wrapper{env: testEnv(), stderr: &stderr}
+	for i := 0; i < 2; i++ {
+		if !w.locate(jar) {
+			t.Fatalf("locate did not find %d-character path %s", len(jar), jar)
+		}
+	}
+
+	warnings := strings.Count(stderr.String(), "MAX_PATH")
+	want := 0
+	if runtime.GOOS == "windows" {
+		want = 1
+	}
+	if warnings != want {
+		t.Errorf("got %d MAX_PATH warnings, want %d; stderr:\n%s", warnings, want, stderr.String())
+	}
+}
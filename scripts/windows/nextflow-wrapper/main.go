@@ -1,60 +1,514 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Exit codes reported by the wrapper itself. These values are stable and may
+// be relied on by callers; any other code is Nextflow's own, passed through
+// unchanged.
+//
+//	1   unexpected wrapper failure (e.g. the executable path is unresolvable)
+//	10  nextflow.jar not found
+//	11  java could not be resolved or started
+//	12  invalid configuration (bad BIOVAULT_* value, argument file, workdir)
+//	13  java is older than BIOVAULT_MIN_JAVA
+//	14  nextflow.jar failed its checksum
+//	15  the bundled Java runtime is incomplete (Windows: a DLL is missing)
+//	16  BIOVAULT_ELEVATE=auto needed administrator rights but UAC was declined
+//	17  BIOVAULT_VERIFY_JAR found nextflow.jar is not a valid jar
+//	124 BIOVAULT_WRAPPER_TIMEOUT expired and Nextflow or the prelaunch hook was killed
+//	128+n signal n interrupted the wrapper before Nextflow started
+const (
+	exitWrapperError      = 1
+	exitJarNotFound       = 10
+	exitJavaFailed        = 11
+	exitBadConfig         = 12
+	exitJavaTooOld        = 13
+	exitJarChecksum       = 14
+	exitJavaBroken        = 15
+	exitElevationDeclined = 16
+	exitJarCorrupt        = 17
+	exitTimeout           = 124
+)
+
+// Test seams: replacing these lets tests observe the chosen java binary and
+// arguments, and anchor resolution to a fake install, without running java.
+var (
+	execCommand = exec.CommandContext
+	executable  = os.Executable
+)
+
+// version is stamped at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+type wrapper struct {
+	env    environ
+	stdout io.Writer
+	stderr io.Writer
+	stdin  io.Reader
+	debug  bool
+	redact []string
+
+	probes       map[string]javaProbe
+	versionCache string
+
+	// blockedJava records that a java candidate exists but failed its probe,
+	// and brokenJava that it failed because the bundled runtime is incomplete.
+	blockedJava bool
+	brokenJava  bool
+	elevated    bool
+	prio        priority
+	events      *eventPipe
+	signals     chan os.Signal
+
+	warnedLongPath    bool
+	warnedBlockedJava bool
+}
+
 func existingFile(path string) bool {
 	info, err := os.Stat(path)
 	return err == nil && !info.IsDir()
 }
 
-func resolveJava(exeDir string) string {
-	if env := os.Getenv("BIOVAULT_BUNDLED_JAVA"); env != "" && existingFile(env) {
-		return env
+// resolveExeDir returns the directory of the real wrapper binary. Symlinks are
+// followed so a linked wrapper still finds the bundled java and jar next to
+// its install; if that fails the unresolved path is used.
+func resolveExeDir() (string, error) {
+	exePath, err := executable()
+	if err != nil {
+		return "", err
+	}
+	exePath, err = normalizeExePath(exePath)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+	return filepath.Dir(exePath), nil
+}
+
+// normalizeExePath makes the executable path absolute and cleaned, with the
+// platform's separator throughout, so the relative ../../java lookups resolve
+// the same way however the caller spelled the invocation path.
+func normalizeExePath(path string) (string, error) {
+	abs, err := filepath.Abs(filepath.FromSlash(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to make %s absolute: %w", path, err)
+	}
+	return abs, nil
+}
+
+// resolveJar picks the Nextflow jar. When BIOVAULT_NEXTFLOW_VERSION is set it
+// must find nextflow-<version>.jar next to the executable or alongside
+// BIOVAULT_NEXTFLOW_JAR; otherwise it uses BIOVAULT_NEXTFLOW_JAR, then
+// nextflow.jar next to the executable. The path is returned even on error so
+// callers can report it.
+func (w *wrapper) resolveJar(exeDir string) (string, error) {
+	if v := w.env.get("BIOVAULT_NEXTFLOW_VERSION"); v != "" {
+		name := "nextflow-" + v + ".jar"
+		dirs := []string{exeDir}
+		if env := w.env.get("BIOVAULT_NEXTFLOW_JAR"); env != "" {
+			dirs = append(dirs, filepath.Dir(env))
+		}
+		for _, dir := range dirs {
+			if jar := filepath.Join(dir, name); w.locate(jar) {
+				return jar, nil
+			}
+		}
+		return filepath.Join(exeDir, name), fmt.Errorf("BIOVAULT_NEXTFLOW_VERSION is %s but %s was not found in %s", v, name, strings.Join(dirs, " or "))
+	}
+
+	if env := w.env.get("BIOVAULT_NEXTFLOW_JAR"); env != "" {
+		if !w.locate(env) {
+			return env, fmt.Errorf("BIOVAULT_NEXTFLOW_JAR points to a missing file: %s", env)
+		}
+		return env, nil
+	}
+
+	jar := filepath.Join(exeDir, "nextflow.jar")
+	if !w.locate(jar) {
+		return jar, fmt.Errorf("nextflow.jar not found next to the wrapper executable: %s", jar)
+	}
+	return jar, nil
+}
+
+// launchTimeout parses BIOVAULT_WRAPPER_TIMEOUT as a Go duration such as 30m.
+// It covers BIOVAULT_PRELAUNCH_CMD and Nextflow together. Zero means no
+// timeout.
+func (w *wrapper) launchTimeout() (time.Duration, error) {
+	value := w.env.get("BIOVAULT_WRAPPER_TIMEOUT")
+	if value == "" {
+		return 0, nil
 	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid BIOVAULT_WRAPPER_TIMEOUT %q: expected a positive duration such as 30m", value)
+	}
+	return timeout, nil
+}
+
+// launchRetries parses BIOVAULT_LAUNCH_RETRIES, the number of extra attempts
+// made when java fails to start at all. It defaults to 0.
+func (w *wrapper) launchRetries() (int, error) {
+	value := w.env.get("BIOVAULT_LAUNCH_RETRIES")
+	if value == "" {
+		return 0, nil
+	}
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return 0, fmt.Errorf("invalid BIOVAULT_LAUNCH_RETRIES %q: expected a non-negative integer", value)
+	}
+	return retries, nil
+}
 
-	rel := filepath.Clean(filepath.Join(exeDir, "..", "..", "java", "windows-x86_64", "bin", "java.exe"))
-	if existingFile(rel) {
-		return rel
+// exitMap parses BIOVAULT_EXIT_MAP, a comma-separated list of from=to pairs
+// such as 1=2,130=143 that translate Nextflow's exit code before the wrapper
+// exits. The wrapper's own exit codes are never remapped.
+func (w *wrapper) exitMap() (map[int]int, error) {
+	value := w.env.get("BIOVAULT_EXIT_MAP")
+	if value == "" {
+		return nil, nil
+	}
+	m := make(map[int]int)
+	for _, entry := range strings.Split(value, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		f, ferr := strconv.Atoi(strings.TrimSpace(from))
+		t, terr := strconv.Atoi(strings.TrimSpace(to))
+		if !ok || ferr != nil || terr != nil || f < 0 || t < 0 || t > 255 {
+			return nil, fmt.Errorf("invalid BIOVAULT_EXIT_MAP entry %q: expected from=to with exit codes such as 1=2", entry)
+		}
+		if _, dup := m[f]; dup {
+			return nil, fmt.Errorf("invalid BIOVAULT_EXIT_MAP: exit code %d is mapped more than once", f)
+		}
+		m[f] = t
 	}
+	return m, nil
+}
 
-	return "java"
+// workDir returns BIOVAULT_WORKDIR, or "" to inherit the wrapper's working
+// directory.
+func (w *wrapper) workDir() (string, error) {
+	dir := w.env.get("BIOVAULT_WORKDIR")
+	if dir == "" {
+		return "", nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid BIOVAULT_WORKDIR: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid BIOVAULT_WORKDIR: not a directory: %s", dir)
+	}
+	return dir, nil
 }
 
 func main() {
-	exePath, err := os.Executable()
+	os.Exit(run(os.Args[1:], os.Environ(), os.Stdout, os.Stderr, os.Stdin))
+}
+
+// run resolves java and nextflow.jar, launches Nextflow with args forwarded
+// and returns the exit code the wrapper should terminate with.
+func run(args []string, env []string, stdout, stderr io.Writer, stdin io.Reader) (code int) {
+	w := &wrapper{env: env, stdout: stdout, stderr: stderr, stdin: stdin}
+	if len(args) > 0 && args[0] == stdinSpecFlag {
+		specArgs, err := w.readStdinSpec()
+		if err != nil {
+			w.logf("%v", err)
+			return exitBadConfig
+		}
+		args = specArgs
+	}
+	if len(args) > 0 && strings.HasPrefix(args[0], elevatedFlag) {
+		specArgs, err := w.readElevationSpec(strings.TrimPrefix(args[0], elevatedFlag))
+		if err != nil {
+			w.logf("%v", err)
+			return exitWrapperError
+		}
+		args = specArgs
+	}
+
+	exeDir, exeErr := resolveExeDir()
+	if exeErr == nil {
+		if err := w.applyConfig(exeDir); err != nil {
+			w.logf("warning: %v", err)
+		}
+	}
+	w.debug = w.env.enabled("BIOVAULT_WRAPPER_DEBUG")
+	w.redact = redactPatterns(w.env)
+
+	origArgs := args
+	flags, args, err := parseWrapperFlags(args)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "failed to resolve executable path:", err)
-		os.Exit(1)
+		w.logf("%v", err)
+		return exitBadConfig
 	}
-	exeDir := filepath.Dir(exePath)
 
-	jar := filepath.Join(exeDir, "nextflow.jar")
-	if !existingFile(jar) {
-		fmt.Fprintln(os.Stderr, "nextflow.jar not found next to nextflow.exe:", jar)
-		os.Exit(1)
+	if flags.mode == doctorMode {
+		return w.doctor()
+	}
+	inv := invocation{args: args, start: time.Now()}
+	if flags.mode == "" && !flags.dryRun {
+		defer func() { w.logInvocation(inv, code) }()
 	}
 
-	java := resolveJava(exeDir)
-	args := []string{"-jar", jar}
-	args = append(args, os.Args[1:]...)
+	if exeErr != nil {
+		w.logf("failed to resolve executable path: %v", exeErr)
+		return exitWrapperError
+	}
+	if !w.env.enabled("BIOVAULT_NO_VERSION_CACHE") {
+		w.versionCache = filepath.Join(w.dataDir(exeDir), versionCacheName)
+	}
 
-	cmd := exec.Command(java, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	cmd.Env = os.Environ()
+	switch flags.mode {
+	case versionMode:
+		w.printVersion(exeDir)
+		return 0
+	case envMode:
+		w.printEnv(exeDir)
+		return 0
+	}
 
-	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitErr.ExitCode())
+	jar, err := w.resolveJar(exeDir)
+	switch {
+	case err != nil && flags.dryRun:
+		w.logf("warning: %v", err)
+	case err != nil:
+		w.logf("%v", err)
+		if w.env.get("BIOVAULT_DIAG_FILE") != "" {
+			java, _ := w.resolveJava(exeDir, jar)
+			inv.java = java
+			w.writeDiagnostic(java, jar, err)
+		}
+		return exitJarNotFound
+	default:
+		if err := verifyJarChecksum(jar); err != nil {
+			w.logf("%v", err)
+			return exitJarChecksum
+		}
+		if w.env.enabled("BIOVAULT_VERIFY_JAR") {
+			if err := verifyJarArchive(jar); err != nil {
+				w.logf("%v", err)
+				return exitJarCorrupt
+			}
 		}
-		fmt.Fprintln(os.Stderr, "failed to run nextflow:", err)
-		os.Exit(1)
 	}
-}
 
+	jvm, err := w.jvmArgs()
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+
+	minJava, err := w.minJava()
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+
+	java, bundled := w.resolveJava(exeDir, jar)
+	inv.java = java
+	if !bundled {
+		if major, ok := w.javaMajor(java); ok && major < minJava {
+			w.logf("%s is Java %d, but Nextflow needs Java %d or newer.", java, major, minJava)
+			w.logf("Install a newer Java and set JAVA_HOME, or reinstall biovault-desktop to restore the bundled runtime.")
+			return exitJavaTooOld
+		}
+	}
+
+	forwarded, err := expandArgFiles(args)
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+	if w.env.enabled("BIOVAULT_EXPAND_ARGS") {
+		forwarded = w.expandArgVars(forwarded)
+	}
+
+	dir, err := w.workDir()
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+
+	nfArgs, err := w.nextflowArgs(forwarded)
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+	if w.env.enabled("BIOVAULT_STRICT_PATHS") {
+		if err := checkPaths(nfArgs, dir); err != nil {
+			w.logf("%v", err)
+			return exitBadConfig
+		}
+	}
+	w.debugArgs(nfArgs)
+
+	launch, err := w.launchArgs(jar)
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+	cmdArgs := append(jvm, launch...)
+	cmdArgs = append(cmdArgs, nfArgs...)
+	inv.args = cmdArgs
+
+	timeout, err := w.launchTimeout()
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	retries, err := w.launchRetries()
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+
+	exitMap, err := w.exitMap()
+	if err != nil {
+		w.logf("%v", err)
+		return exitBadConfig
+	}
+
+	w.signals = interceptSignals()
+	defer signal.Stop(w.signals)
+	launchCtx, stopWatching := watchSignals(ctx, w.signals)
+	defer stopWatching()
+	interrupted := func() (int, bool) {
+		sig := stopWatching()
+		if sig == nil {
+			return 0, false
+		}
+		w.logf("interrupted by %v before nextflow started", sig)
+		return signalExitCode(sig), true
+	}
+
+	w.prio = w.priority()
+	childEnv := w.childEnv(exeDir)
+	if w.env.enabled("BIOVAULT_MANAGED_TEMP") {
+		env, cleanup, err := w.managedTemp(childEnv)
+		if err != nil {
+			w.logf("%v", err)
+			return exitWrapperError
+		}
+		defer cleanup()
+		childEnv = env
+	}
+	childOut, childErr := w.stdout, w.stderr
+	newCmd := func() *exec.Cmd {
+		cmd := execCommand(ctx, java, cmdArgs...)
+		cmd.Stdout = childOut
+		cmd.Stderr = childErr
+		cmd.Stdin = childStdin(w.stdin)
+		cmd.Env = childEnv
+		cmd.Dir = dir
+		configureChild(cmd)
+		setPriority(cmd, w.prio)
+		cmd.Cancel = func() error { return killChild(cmd.Process) }
+		cmd.WaitDelay = childWaitDelay
+		return cmd
+	}
+	cmd := newCmd()
+	w.debugCommand(cmd)
+
+	if flags.tracePath != "" {
+		if err := w.writeExecTrace(flags.tracePath, cmd); err != nil {
+			w.logf("warning: failed to write exec trace: %v", err)
+		}
+	}
+
+	if flags.dryRun {
+		w.printDryRun(java, jar, cmdArgs)
+		return 0
+	}
+
+	if code, relaunched := w.elevate(jar, origArgs); relaunched {
+		return code
+	}
+
+	if !w.env.enabled("BIOVAULT_NO_UTF8_CONSOLE") {
+		defer useUTF8Console()()
+	}
+
+	if w.env.enabled("BIOVAULT_DANGLING_LOCK_CLEANUP") {
+		w.cleanStaleLocks(dir)
+	}
+
+	code = w.runPrelaunch(launchCtx, cmd.Env, cmd.Dir)
+	if code, ok := interrupted(); ok {
+		return code
+	}
+	if code != 0 {
+		return code
+	}
+
+	if path := w.env.get("BIOVAULT_TEE_LOG"); path != "" {
+		tee, err := w.openTeeLog(path)
+		if err != nil {
+			w.logf("warning: BIOVAULT_TEE_LOG: %v", err)
+		} else {
+			defer tee.Close()
+			childOut = io.MultiWriter(w.stdout, tee)
+			childErr = io.MultiWriter(w.stderr, tee)
+		}
+	}
+
+	w.events = w.openEventPipe()
+	defer w.events.Close()
+	w.events.emit(resolvedEvent{Event: "resolved", Java: java, Jar: jar})
+
+	if code, ok := interrupted(); ok {
+		return code
+	}
+	start := time.Now()
+	code, err = w.executeWithRetry(ctx, newCmd, retries)
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		w.logf("nextflow did not finish within BIOVAULT_WRAPPER_TIMEOUT (%s) and was killed", timeout)
+		code = exitTimeout
+	case err != nil && w.brokenJava:
+		w.logf("failed to run nextflow: %v", err)
+		w.writeDiagnostic(java, jar, err)
+		code = exitJavaBroken
+	case err != nil && java == "java" && !w.blockedJava && errors.Is(err, exec.ErrNotFound):
+		w.logf("No bundled Java found and no 'java' on PATH; install Java %d or newer, or reinstall biovault-desktop.", minJava)
+		w.writeDiagnostic(java, jar, err)
+		code = exitJavaFailed
+	case err != nil:
+		w.logf("failed to run nextflow: %v", err)
+		w.writeDiagnostic(java, jar, err)
+		code = exitJavaFailed
+	case brokenRuntimeExit(code):
+		w.logf(incompleteRuntimeMessage)
+		code = exitJavaBroken
+	default:
+		if mapped, ok := exitMap[code]; ok {
+			w.debugf("BIOVAULT_EXIT_MAP: mapping exit code %d to %d", code, mapped)
+			code = mapped
+		}
+	}
+	elapsed := time.Since(start)
+	w.events.emit(exitedEvent{Event: "exited", Code: code, DurationMs: elapsed.Milliseconds()})
+	if w.env.enabled("BIOVAULT_WRAPPER_SUMMARY") {
+		w.logf("nextflow exited with code %d after %s", code, roundDuration(elapsed))
+	}
+	return code
+}
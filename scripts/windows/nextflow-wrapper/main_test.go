@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestHelperProcess is not a real test. fakeExecCommand runs the test binary
+// itself in its place, and it plays java: it answers -version with a Java 21
+// banner, or fails it with HELPER_VERSION_EXIT, and otherwise prints the
+// binary and arguments it was given as JSON.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	args = args[1:]
+	if len(args) == 2 && args[1] == "-version" {
+		if code := os.Getenv("HELPER_VERSION_EXIT"); code != "" {
+			n, _ := strconv.ParseInt(code, 0, 64)
+			os.Exit(int(n))
+		}
+		fmt.Fprintln(os.Stderr, `openjdk version "21.0.1" 2023-10-17`)
+		os.Exit(0)
+	}
+	json.NewEncoder(os.Stdout).Encode(args)
+	os.Exit(0)
+}
+
+func fakeExecCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, os.Args[0], append([]string{"-test.run=TestHelperProcess", "--", name}, args...)...)
+}
+
+// testEnv is a minimal environment for run that lets fakeExecCommand's
+// helper start, plus kv.
+func testEnv(kv ...string) []string {
+	env := []string{"GO_WANT_HELPER_PROCESS=1", "PATH=" + os.Getenv("PATH")}
+	if root := os.Getenv("SYSTEMROOT"); root != "" {
+		env = append(env, "SYSTEMROOT="+root)
+	}
+	return append(env, kv...)
+}
+
+// fakeInstall lays out a bundled install in a temp dir, points the
+// executable and execCommand seams at it and returns the wrapper's directory
+// with the paths of its bundled java and nextflow.jar.
+func fakeInstall(t *testing.T) (exeDir, java, jar string) {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform := javaPlatform(runtime.GOOS, runtime.GOARCH)
+	exeDir = filepath.Join(root, "nextflow", platform)
+	java = filepath.Join(root, "java", platform, "bin", javaBinary(runtime.GOOS))
+	jar = filepath.Join(exeDir, "nextflow.jar")
+	for _, path := range []string{jar, java} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stubSeams(t, filepath.Join(exeDir, "nextflow"))
+	return exeDir, java, jar
+}
+
+func stubSeams(t *testing.T, exe string) {
+	t.Helper()
+	origExec, origExecutable := execCommand, executable
+	t.Cleanup(func() { execCommand, executable = origExec, origExecutable })
+	execCommand = fakeExecCommand
+	executable = func() (string, error) { return exe, nil }
+}
+
+func TestRunLaunchesBundledJava(t *testing.T) {
+	_, java, jar := fakeInstall(t)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"run", "hello"}, testEnv(), &stdout, &stderr, nil)
+	if code != 0 {
+		t.Fatalf("run returned %d, stderr:\n%s", code, stderr.String())
+	}
+
+	var got []string
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected child output %q: %v", stdout.String(), err)
+	}
+	want := []string{java, "-jar", jar, "run", "hello"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("launched %q, want %q", got, want)
+	}
+}
+
+func TestRunMissingJar(t *testing.T) {
+	_, _, jar := fakeInstall(t)
+	if err := os.Remove(jar); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"run"}, testEnv(), &stdout, &stderr, nil); code != exitJarNotFound {
+		t.Errorf("run returned %d, want %d (stderr: %s)", code, exitJarNotFound, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("nextflow was launched: %s", stdout.String())
+	}
+}
+
+func TestResolveExeDirFollowsSymlink(t *testing.T) {
+	exeDir, java, _ := fakeInstall(t)
+	exe := filepath.Join(exeDir, "nextflow")
+	if err := os.WriteFile(exe, nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(t.TempDir(), "nextflow")
+	if err := os.Symlink(exe, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	stubSeams(t, link)
+
+	got, err := resolveExeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != exeDir {
+		t.Errorf("resolveExeDir() = %s, want %s", got, exeDir)
+	}
+
+	w := &wrapper{env: testEnv(), stderr: &bytes.Buffer{}}
+	if got, _ := w.resolveJava(got, ""); got != java {
+		t.Errorf("resolveJava anchored to %s, want %s", got, java)
+	}
+}
+
+func TestRunMissingJarSkipsJavaProbe(t *testing.T) {
+	_, _, jar := fakeInstall(t)
+	if err := os.Remove(jar); err != nil {
+		t.Fatal(err)
+	}
+	probed := false
+	execCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		probed = true
+		return fakeExecCommand(ctx, name, args...)
+	}
+
+	run([]string{"run"}, testEnv(), &bytes.Buffer{}, &bytes.Buffer{}, nil)
+	if probed {
+		t.Error("java was probed although no diagnostic file was requested")
+	}
+}
+
+func TestNormalizeExePath(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type test struct{ in, want string }
+	tests := []test{
+		{"nextflow", filepath.Join(cwd, "nextflow")},
+		{"./bin/../nextflow", filepath.Join(cwd, "nextflow")},
+		{"/opt/biovault//nextflow/linux-x86_64/./nextflow", "/opt/biovault/nextflow/linux-x86_64/nextflow"},
+		{"/opt/biovault/java/../nextflow/linux-x86_64/nextflow", "/opt/biovault/nextflow/linux-x86_64/nextflow"},
+	}
+	if runtime.GOOS == "windows" {
+		want := `C:\biovault\nextflow\windows-x86_64\nextflow.exe`
+		tests = []test{
+			{"nextflow.exe", filepath.Join(cwd, "nextflow.exe")},
+			{`.\bin/..\nextflow.exe`, filepath.Join(cwd, "nextflow.exe")},
+			{`C:\biovault\nextflow\windows-x86_64\nextflow.exe`, want},
+			{`C:/biovault/nextflow/windows-x86_64/nextflow.exe`, want},
+			{`C:/biovault\nextflow/windows-x86_64\nextflow.exe`, want},
+			{`C:\biovault\\nextflow//windows-x86_64\.\nextflow.exe`, want},
+			{`C:\biovault/java\..\nextflow/windows-x86_64\nextflow.exe`, want},
+		}
+	}
+	for _, tt := range tests {
+		got, err := normalizeExePath(tt.in)
+		if err != nil {
+			t.Errorf("normalizeExePath(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeExePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestResolveExeDirIgnoresSpelling checks that however the executable path
+// is spelled, the bundled java is found relative to the same directory.
+func TestResolveExeDirIgnoresSpelling(t *testing.T) {
+	exeDir, java, _ := fakeInstall(t)
+	root := filepath.Dir(filepath.Dir(exeDir))
+	platform := filepath.Base(exeDir)
+	spellings := []string{
+		filepath.Join(exeDir, "nextflow"),
+		filepath.ToSlash(exeDir) + "//nextflow",
+		filepath.ToSlash(root) + "/java/../nextflow/./" + platform + "/nextflow",
+	}
+	if runtime.GOOS == "windows" {
+		spellings = append(spellings, filepath.ToSlash(root)+`/nextflow\`+platform+"/nextflow.exe")
+	}
+	for _, exe := range spellings {
+		stubSeams(t, exe)
+		got, err := resolveExeDir()
+		if err != nil {
+			t.Errorf("resolveExeDir() for %s: %v", exe, err)
+			continue
+		}
+		if got != exeDir {
+			t.Errorf("resolveExeDir() for %s = %s, want %s", exe, got, exeDir)
+		}
+		w := &wrapper{env: testEnv(), stderr: &bytes.Buffer{}}
+		if got, _ := w.resolveJava(got, ""); got != java {
+			t.Errorf("resolveJava for %s = %s, want %s", exe, got, java)
+		}
+	}
+}
+
+// noSystemJava is an execCommand for a machine without java on PATH: the
+// bundled runtime still runs as the test helper, bare java is not found.
+func noSystemJava(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if name == "java" {
+		return exec.CommandContext(ctx, "biovault-test-no-such-java")
+	}
+	return fakeExecCommand(ctx, name, args...)
+}
+
+func TestRunUnrunnableBundledJava(t *testing.T) {
+	tests := []struct {
+		name    string
+		exit    string
+		broken  bool
+		code    int
+		message string
+	}{
+		{"blocked", "1", false, exitJavaFailed, "failed to run nextflow"},
+		{"incomplete", "0xC0000135", true, exitJavaBroken, incompleteRuntimeMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.broken && runtime.GOOS != "windows" {
+				t.Skip("loader failures are only recognised on Windows")
+			}
+			fakeInstall(t)
+			execCommand = noSystemJava
+
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"run"}, testEnv("HELPER_VERSION_EXIT="+tt.exit), &stdout, &stderr, nil)
+			if code != tt.code {
+				t.Errorf("run returned %d, want %d; stderr:\n%s", code, tt.code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.message) {
+				t.Errorf("stderr does not mention %q:\n%s", tt.message, stderr.String())
+			}
+			if strings.Contains(stderr.String(), "No bundled Java found") {
+				t.Errorf("a bundled java was found, but stderr says none was:\n%s", stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunNoJavaAtAll(t *testing.T) {
+	_, java, _ := fakeInstall(t)
+	if err := os.Remove(java); err != nil {
+		t.Fatal(err)
+	}
+	execCommand = noSystemJava
+
+	var stderr bytes.Buffer
+	if code := run([]string{"run"}, testEnv(), &bytes.Buffer{}, &stderr, nil); code != exitJavaFailed {
+		t.Errorf("run returned %d, want %d; stderr:\n%s", code, exitJavaFailed, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "No bundled Java found") {
+		t.Errorf("stderr does not explain that no java was found:\n%s", stderr.String())
+	}
+}
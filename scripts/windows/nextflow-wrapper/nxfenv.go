@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dataDir is where the wrapper keeps Nextflow state: $BIOVAULT_HOME/data,
+// which the desktop app also uses for NXF_HOME, or a data directory next to
+// the wrapper when it runs outside the app.
+func (w *wrapper) dataDir(exeDir string) string {
+	if home := w.env.get("BIOVAULT_HOME"); home != "" {
+		return filepath.Join(home, "data")
+	}
+	return filepath.Join(exeDir, "data")
+}
+
+// childEnv builds the environment for Nextflow from the wrapper's own.
+func (w *wrapper) childEnv(exeDir string) environ {
+	env := w.nxfDefaults(w.env, exeDir)
+	env = mergeJavaToolOptions(env)
+	env = offlineDefaults(env)
+	return env
+}
+
+// offlineDefaults implements BIOVAULT_OFFLINE for air-gapped installs:
+// NXF_OFFLINE stops Nextflow fetching plugins and pipelines, and
+// NXF_DISABLE_CHECK_LATEST skips the startup version check. Either variable
+// the caller already set is respected.
+func offlineDefaults(env environ) environ {
+	if !env.enabled("BIOVAULT_OFFLINE") {
+		return env
+	}
+	env = env.withDefault("NXF_OFFLINE", "true")
+	return env.withDefault("NXF_DISABLE_CHECK_LATEST", "true")
+}
+
+// nxfDefaults points NXF_HOME and NXF_TEMP at directories under dataDir so
+// runs do not share ~/.nextflow with other installs. Values the caller already
+// set are left alone, and BIOVAULT_NO_NXF_DEFAULTS=1 disables it entirely.
+func (w *wrapper) nxfDefaults(env environ, exeDir string) environ {
+	if env.enabled("BIOVAULT_NO_NXF_DEFAULTS") {
+		return env
+	}
+
+	home := filepath.Join(w.dataDir(exeDir), "nextflow")
+	for _, d := range []struct{ key, dir string }{
+		{"NXF_HOME", home},
+		{"NXF_TEMP", filepath.Join(home, "tmp")},
+	} {
+		if _, ok := env.lookup(d.key); ok {
+			continue
+		}
+		if err := os.MkdirAll(d.dir, 0o755); err != nil {
+			continue
+		}
+		env = env.withDefault(d.key, d.dir)
+	}
+	return env
+}
+
+// mergeJavaToolOptions appends BIOVAULT_JAVA_TOOL_OPTIONS to any inherited
+// JAVA_TOOL_OPTIONS rather than replacing it, so corporate defaults survive.
+func mergeJavaToolOptions(env environ) environ {
+	ours := strings.TrimSpace(env.get("BIOVAULT_JAVA_TOOL_OPTIONS"))
+	if ours == "" {
+		return env
+	}
+	theirs := strings.TrimSpace(env.get("JAVA_TOOL_OPTIONS"))
+	if theirs == "" {
+		return env.with("JAVA_TOOL_OPTIONS", ours)
+	}
+	return env.with("JAVA_TOOL_OPTIONS", theirs+" "+ours)
+}
@@ -0,0 +1,38 @@
+package main
+
+// javaPlatform names the bundled runtime directory for goos/goarch. The names
+// follow the bundler's layout (bundled/java/<platform>), which uses Rust's
+// OS/ARCH constants: windows-x86_64, macos-aarch64, linux-x86_64 and so on.
+func javaPlatform(goos, goarch string) string {
+	osName := goos
+	if goos == "darwin" {
+		osName = "macos"
+	}
+
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	}
+	return osName + "-" + arch
+}
+
+// javaPlatforms lists the bundled runtime directories to try, best match
+// first. On arm64 Windows and macOS an x86_64 runtime still works under
+// emulation, so it is kept as a fallback.
+func javaPlatforms(goos, goarch string) []string {
+	platforms := []string{javaPlatform(goos, goarch)}
+	if goarch == "arm64" && (goos == "windows" || goos == "darwin") {
+		platforms = append(platforms, javaPlatform(goos, "amd64"))
+	}
+	return platforms
+}
+
+func javaBinary(goos string) string {
+	if goos == "windows" {
+		return "java.exe"
+	}
+	return "java"
+}
@@ -0,0 +1,29 @@
+package main
+
+import "strings"
+
+// priority is the scheduling priority requested with BIOVAULT_PRIORITY for
+// background runs.
+type priority int
+
+const (
+	priorityNormal priority = iota
+	priorityBelowNormal
+	priorityLow
+)
+
+// priority parses BIOVAULT_PRIORITY. An unknown value is reported and
+// treated as normal rather than failing the launch.
+func (w *wrapper) priority() priority {
+	value := w.env.get("BIOVAULT_PRIORITY")
+	switch strings.ToLower(value) {
+	case "", "normal":
+		return priorityNormal
+	case "belownormal":
+		return priorityBelowNormal
+	case "low":
+		return priorityLow
+	}
+	w.logf("warning: ignoring unknown BIOVAULT_PRIORITY %q (expected low, belownormal or normal)", value)
+	return priorityNormal
+}
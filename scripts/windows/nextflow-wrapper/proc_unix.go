@@ -0,0 +1,114 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// configureChild puts the child in its own process group, so killChild and
+// relayed signals reach everything Nextflow starts. When stdin is a terminal
+// whose foreground group is the wrapper's, the child stays in that group
+// instead: java can read the tty without being stopped by SIGTTIN, and the
+// terminal delivers Ctrl+C and Ctrl+Z to the wrapper and the child together,
+// so the calling shell gets the terminal back when the job is stopped.
+func configureChild(cmd *exec.Cmd) {
+	if foregroundTerminal(cmd.Stdin) {
+		return
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// foregroundTerminal reports whether stdin is a terminal whose foreground
+// process group is the wrapper's own.
+func foregroundTerminal(stdin io.Reader) bool {
+	f, ok := stdin.(*os.File)
+	if !ok || f == nil {
+		return false
+	}
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return false
+	}
+	return int(pgrp) == syscall.Getpgrp()
+}
+
+// ownGroup reports whether proc leads its own process group, which it does
+// unless configureChild left it in the wrapper's terminal group.
+func ownGroup(proc *os.Process) bool {
+	pgid, err := syscall.Getpgid(proc.Pid)
+	return err == nil && pgid == proc.Pid
+}
+
+// signalChild relays sig to the child's group. A child sharing the wrapper's
+// terminal group has already had a terminal SIGINT delivered to it, so only
+// other signals are relayed, to the child alone.
+func signalChild(proc *os.Process, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		s = syscall.SIGINT
+	}
+	if !ownGroup(proc) {
+		if s == syscall.SIGINT {
+			return nil
+		}
+		return proc.Signal(s)
+	}
+	return syscall.Kill(-proc.Pid, s)
+}
+
+func killChild(proc *os.Process) error {
+	if !ownGroup(proc) {
+		return proc.Kill()
+	}
+	return syscall.Kill(-proc.Pid, syscall.SIGKILL)
+}
+
+// Nice values for each priority. Unix has no way to start a process at a
+// lower priority, so it is applied by applyPriority right after start, to the
+// child's whole process group: that also catches any process it has already
+// spawned and, on Linux, where nice is per thread, the JVM's threads. A child
+// in the wrapper's terminal group lowers the idle wrapper, and the rest of
+// its job, with it.
+var priorityNice = map[priority]int{
+	priorityBelowNormal: 10,
+	priorityLow:         19,
+}
+
+func setPriority(cmd *exec.Cmd, p priority) {}
+
+func applyPriority(proc *os.Process, p priority) error {
+	nice, ok := priorityNice[p]
+	if !ok {
+		return nil
+	}
+	pgid, err := syscall.Getpgid(proc.Pid)
+	if err != nil {
+		return err
+	}
+	return syscall.Setpriority(syscall.PRIO_PGRP, pgid, nice)
+}
+
+// processAlive reports whether pid is running. EPERM means it exists but
+// belongs to another user, which still counts as alive.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func shellCommand(ctx context.Context, env environ, script string) *exec.Cmd {
+	return execCommand(ctx, "/bin/sh", "-c", script)
+}
+
+// brokenRuntimeExit reports loader failures from a partially extracted JRE.
+// The Unix loader reports a missing libjvm in java's own output and exit
+// status 1, which cannot be told apart from a real failure, so nothing is
+// detected here.
+func brokenRuntimeExit(code int) bool {
+	return false
+}
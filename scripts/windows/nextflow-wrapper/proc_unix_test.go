@@ -0,0 +1,64 @@
+//go:build !windows
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestApplyPriorityLowersProcessGroup starts a child that has already forked
+// when the priority is applied; the fork must be lowered too.
+func TestApplyPriorityLowersProcessGroup(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "(echo forked; sleep 0.2; nice) & wait")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	configureChild(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	out := bufio.NewScanner(stdout)
+	if !out.Scan() || out.Text() != "forked" {
+		t.Fatalf("child did not fork: %q", out.Text())
+	}
+
+	if err := applyPriority(cmd.Process, priorityLow); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Scan() {
+		t.Fatal("no output from nice")
+	}
+	if got := strings.TrimSpace(out.Text()); got != "19" {
+		t.Errorf("forked process runs at nice %s, want 19", got)
+	}
+}
+
+// TestKillChildInSharedGroup covers a child configureChild left in the
+// wrapper's terminal group: it has no group of its own to kill, and a
+// terminal interrupt has already reached it.
+func TestKillChildInSharedGroup(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if ownGroup(cmd.Process) {
+		t.Fatal("child unexpectedly leads its own process group")
+	}
+	if err := signalChild(cmd.Process, os.Interrupt); err != nil {
+		t.Errorf("signalChild(SIGINT) = %v", err)
+	}
+	if err := killChild(cmd.Process); err != nil {
+		t.Fatalf("killChild = %v", err)
+	}
+	err := cmd.Wait()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || status.Signal() != syscall.SIGKILL {
+		t.Errorf("child ended with %v, want SIGKILL", err)
+	}
+}
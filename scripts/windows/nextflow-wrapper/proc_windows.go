@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+// configureChild leaves the child in the wrapper's console process group.
+// CTRL_C_EVENT cannot be targeted at a group created with
+// CREATE_NEW_PROCESS_GROUP, and the JVM treats CTRL_BREAK_EVENT as a
+// thread-dump request rather than a shutdown, so the only way for Nextflow's
+// shutdown hooks to run is for the console to deliver Ctrl+C to it directly.
+func configureChild(cmd *exec.Cmd) {}
+
+// signalChild is a no-op: console control events are broadcast to every
+// process attached to the console, so the child has already received it.
+func signalChild(proc *os.Process, sig os.Signal) error {
+	return nil
+}
+
+// killChild terminates the child's job object, taking every process
+// Nextflow spawned down with it, or just the child if it has no job.
+func killChild(proc *os.Process) error {
+	if terminateJob(proc.Pid) {
+		return nil
+	}
+	return proc.Kill()
+}
+
+// Priority classes passed in CreationFlags, so the child never runs at
+// normal priority.
+const (
+	belowNormalPriorityClass = 0x00004000
+	idlePriorityClass        = 0x00000040
+)
+
+var priorityClass = map[priority]uint32{
+	priorityBelowNormal: belowNormalPriorityClass,
+	priorityLow:         idlePriorityClass,
+}
+
+func setPriority(cmd *exec.Cmd, p priority) {
+	class, ok := priorityClass[p]
+	if !ok {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= class
+}
+
+// applyPriority is a no-op: the priority class was set at creation.
+func applyPriority(proc *os.Process, p priority) error {
+	return nil
+}
+
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// processAlive reports whether pid is running. A process that exists but
+// cannot be opened, such as an elevated one, counts as alive.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
+
+// shellCommand runs script through cmd.exe. The command line is passed
+// verbatim with /S so cmd.exe strips exactly the outer quotes and the script's
+// own quoting is preserved.
+func shellCommand(ctx context.Context, env environ, script string) *exec.Cmd {
+	shell := env.get("COMSPEC")
+	if shell == "" {
+		shell = "cmd.exe"
+	}
+	cmd := execCommand(ctx, shell)
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd.exe /S /C "` + script + `"`}
+	return cmd
+}
+
+// Loader failures reported as the process exit status when java.exe starts
+// but a DLL it depends on, typically server\jvm.dll, is missing or unusable.
+const (
+	statusDLLNotFound        = 0xC0000135
+	statusEntryPointNotFound = 0xC0000139
+)
+
+func brokenRuntimeExit(code int) bool {
+	switch uint32(code) {
+	case statusDLLNotFound, statusEntryPointNotFound:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// defaultRedactPatterns are the variable-name globs whose values are hidden
+// in debug and log output. BIOVAULT_LOG_REDACT adds more, comma-separated.
+var defaultRedactPatterns = []string{"*SECRET*", "*TOKEN*", "*PASSWORD*", "*KEY*"}
+
+func redactPatterns(env environ) []string {
+	patterns := append([]string(nil), defaultRedactPatterns...)
+	for _, p := range strings.Split(env.get("BIOVAULT_LOG_REDACT"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// scrub returns a KEY=VALUE entry fit for display, with the value replaced by
+// *** if the key matches a redaction pattern (case-insensitively). It is only
+// for output; the child's environment is never scrubbed.
+func (w *wrapper) scrub(kv string) string {
+	key, _, ok := strings.Cut(kv, "=")
+	if ok && w.sensitive(key) {
+		return key + "=***"
+	}
+	return kv
+}
+
+func (w *wrapper) sensitive(key string) bool {
+	key = strings.ToUpper(key)
+	for _, p := range w.redact {
+		if ok, _ := path.Match(strings.ToUpper(p), key); ok {
+			return true
+		}
+	}
+	return false
+}
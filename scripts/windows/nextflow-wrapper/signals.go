@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// forceKillWindow is how long after a forwarded interrupt a second one
+// escalates to killing the child outright.
+const forceKillWindow = 5 * time.Second
+
+// interceptSignals stops interrupts from terminating the wrapper. run calls it
+// before creating anything that must be cleaned up, such as the managed temp
+// directory, so there is no window in which Ctrl+C kills the wrapper, leaks
+// those resources or orphans java. The caller stops it with signal.Stop.
+func interceptSignals() chan os.Signal {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	return sigs
+}
+
+// watchSignals covers the time before the child starts, when there is no
+// process to relay an interrupt to: the first signal on sigs cancels the
+// returned context, killing the prelaunch hook if one is running. stop ends
+// the watch, leaving later signals to forwardSignals, and returns the signal
+// that cancelled the context, or nil. It may be called more than once.
+func watchSignals(ctx context.Context, sigs chan os.Signal) (context.Context, func() os.Signal) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	caught := make(chan os.Signal, 1)
+	go func() {
+		select {
+		case sig := <-sigs:
+			cancel()
+			caught <- sig
+		case <-done:
+			caught <- nil
+		}
+	}()
+
+	var once sync.Once
+	var sig os.Signal
+	return ctx, func() os.Signal {
+		once.Do(func() {
+			close(done)
+			sig = <-caught
+		})
+		return sig
+	}
+}
+
+// signalExitCode is the conventional shell exit code for dying of sig.
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return exitWrapperError
+}
+
+func forwardSignals(sigs chan os.Signal, proc *os.Process) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		var last time.Time
+		for {
+			select {
+			case sig := <-sigs:
+				if !last.IsZero() && time.Since(last) < forceKillWindow {
+					_ = killChild(proc)
+					continue
+				}
+				last = time.Now()
+				_ = signalChild(proc, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchSignalsCancels(t *testing.T) {
+	sigs := make(chan os.Signal, 2)
+	ctx, stop := watchSignals(context.Background(), sigs)
+	sigs <- syscall.SIGTERM
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("a signal did not cancel the context")
+	}
+	if sig := stop(); sig != syscall.SIGTERM {
+		t.Errorf("stop() = %v, want %v", sig, syscall.SIGTERM)
+	}
+	if sig := stop(); sig != syscall.SIGTERM {
+		t.Errorf("second stop() = %v, want %v", sig, syscall.SIGTERM)
+	}
+	if code := signalExitCode(syscall.SIGTERM); code != 143 {
+		t.Errorf("signalExitCode(SIGTERM) = %d, want 143", code)
+	}
+}
+
+func TestWatchSignalsHandsOver(t *testing.T) {
+	sigs := make(chan os.Signal, 2)
+	ctx, stop := watchSignals(context.Background(), sigs)
+	if sig := stop(); sig != nil {
+		t.Fatalf("stop() = %v without a signal", sig)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("context cancelled without a signal: %v", ctx.Err())
+	}
+
+	// Once stopped, signals are left for forwardSignals.
+	sigs <- os.Interrupt
+	select {
+	case sig := <-sigs:
+		if sig != os.Interrupt {
+			t.Errorf("got %v, want %v", sig, os.Interrupt)
+		}
+	case <-time.After(time.Second):
+		t.Error("the watcher consumed a signal after it was stopped")
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// childStdin returns the reader to hand to Nextflow. A console, pipe, socket
+// or redirected file is passed through; anything else, such as the invalid
+// handle a detached or service launch leaves behind, is replaced by nil, which
+// exec connects to the null device.
+func childStdin(stdin io.Reader) io.Reader {
+	f, ok := stdin.(*os.File)
+	if !ok {
+		return stdin
+	}
+	if f == nil || !stdinUsable(f) {
+		return nil
+	}
+	return f
+}
+
+func stdinUsable(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode.IsRegular() || mode&(os.ModeCharDevice|os.ModeNamedPipe|os.ModeSocket) != 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const stdinSpecFlag = "--wrapper-stdin-spec"
+
+// launchSpec is the JSON object read from stdin with --wrapper-stdin-spec, so
+// the desktop app can pass arguments and environment without any quoting.
+type launchSpec struct {
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+	Workdir string            `json:"workdir"`
+}
+
+// readStdinSpec decodes a launchSpec from stdin and applies it: env entries
+// override the inherited environment, workdir is used as BIOVAULT_WORKDIR,
+// and args replaces the command line. Once stdin is consumed the child gets an
+// empty stdin instead.
+func (w *wrapper) readStdinSpec() ([]string, error) {
+	if w.stdin == nil {
+		return nil, fmt.Errorf("%s: no stdin to read the launch spec from", stdinSpecFlag)
+	}
+	var spec launchSpec
+	dec := json.NewDecoder(w.stdin)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("%s: invalid launch spec on stdin: %w", stdinSpecFlag, err)
+	}
+	w.stdin = nil
+
+	keys := make([]string, 0, len(spec.Env))
+	for key := range spec.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w.env = w.env.with(key, spec.Env[key])
+	}
+	if spec.Workdir != "" {
+		w.env = w.env.with("BIOVAULT_WORKDIR", spec.Workdir)
+	}
+	return spec.Args, nil
+}
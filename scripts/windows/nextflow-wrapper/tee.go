@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// teeLog is the BIOVAULT_TEE_LOG copy of the child's output. Stdout and
+// stderr are copied by separate goroutines, so writes are serialized. A write
+// error disables the copy with a single warning and is never reported to the
+// caller: io.MultiWriter stops at the first failing writer, and a full disk
+// must not also cut off the console.
+type teeLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	logf   func(format string, args ...any)
+	failed bool
+}
+
+func (w *wrapper) openTeeLog(path string) (*teeLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &teeLog{file: f, logf: w.logf}, nil
+}
+
+func (t *teeLog) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failed {
+		return len(p), nil
+	}
+	if _, err := t.file.Write(p); err != nil {
+		t.failed = true
+		t.logf("warning: BIOVAULT_TEE_LOG write failed, continuing with console output only: %v", err)
+	}
+	return len(p), nil
+}
+
+func (t *teeLog) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.file.Close()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// managedTemp implements BIOVAULT_MANAGED_TEMP: it creates a fresh temp
+// directory for this launch and returns the child environment pointed at it,
+// together with a function that removes it. The caller defers the cleanup in
+// run, which always returns after the child has exited, including when an
+// interrupt was forwarded or the child was force-killed, so the directory
+// does not outlive the run. BIOVAULT_KEEP_TEMP=1 keeps it for inspection.
+func (w *wrapper) managedTemp(env environ) (environ, func(), error) {
+	dir, err := os.MkdirTemp("", "biovault-nextflow-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create managed temp directory: %w", err)
+	}
+	w.debugf("managed temp: %s", dir)
+	for _, key := range []string{"NXF_TEMP", "TMPDIR", "TEMP", "TMP"} {
+		env = env.with(key, dir)
+	}
+
+	cleanup := func() {
+		if w.env.enabled("BIOVAULT_KEEP_TEMP") {
+			w.logf("keeping managed temp directory: %s", dir)
+			return
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			w.logf("warning: failed to remove managed temp directory: %v", err)
+		}
+	}
+	return env, cleanup, nil
+}
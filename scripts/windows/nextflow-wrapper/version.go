@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func (w *wrapper) printVersion(exeDir string) {
+	jar, _ := w.resolveJar(exeDir)
+	java, _ := w.resolveJava(exeDir, jar)
+
+	fmt.Fprintln(w.stdout, "nextflow-wrapper", version)
+	fmt.Fprintf(w.stdout, "java: %s (%s)\n", java, presence(javaExists(java)))
+	fmt.Fprintf(w.stdout, "jar:  %s (%s)\n", jar, presence(existingFile(jar)))
+}
+
+// javaExists reports whether java can be found, searching PATH when it is the
+// bare "java" fallback.
+func javaExists(java string) bool {
+	if java == "java" {
+		_, err := exec.LookPath(java)
+		return err == nil
+	}
+	return existingFile(java)
+}
+
+func presence(ok bool) string {
+	if ok {
+		return "found"
+	}
+	return "missing"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const versionCacheName = "java-version-cache.json"
+
+// versionCacheEntry remembers the java -version output of one binary. It is
+// only trusted while the binary's size and modification time are unchanged.
+type versionCacheEntry struct {
+	ModTime int64  `json:"modTime"`
+	Size    int64  `json:"size"`
+	Output  string `json:"output"`
+}
+
+// cachedProbe returns the cached java -version output for java, if the cache
+// is enabled and the entry matches the binary on disk.
+func (w *wrapper) cachedProbe(java string) (string, bool) {
+	if w.versionCache == "" {
+		return "", false
+	}
+	info, ok := javaFileInfo(java)
+	if !ok {
+		return "", false
+	}
+	entry, ok := readVersionCache(w.versionCache)[info.path]
+	if !ok || entry.ModTime != info.modTime || entry.Size != info.size {
+		return "", false
+	}
+	w.debugf("java version cache: hit for %s", info.path)
+	return entry.Output, true
+}
+
+// storeProbe records a successful probe. The cache is rewritten through a
+// temp file and a rename, so concurrent wrappers never see a partial file;
+// when two race, one of their entries is simply probed again next time.
+func (w *wrapper) storeProbe(java, output string) {
+	if w.versionCache == "" {
+		return
+	}
+	info, ok := javaFileInfo(java)
+	if !ok {
+		return
+	}
+	cache := readVersionCache(w.versionCache)
+	cache[info.path] = versionCacheEntry{ModTime: info.modTime, Size: info.size, Output: output}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+
+	dir := filepath.Dir(w.versionCache)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(dir, versionCacheName+".*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), w.versionCache)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func readVersionCache(path string) map[string]versionCacheEntry {
+	cache := make(map[string]versionCacheEntry)
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+type javaFile struct {
+	path    string
+	modTime int64
+	size    int64
+}
+
+// javaFileInfo identifies the binary behind java, looking the bare "java"
+// fallback up on PATH.
+func javaFileInfo(java string) (javaFile, bool) {
+	path := java
+	if java == "java" {
+		var err error
+		if path, err = exec.LookPath(java); err != nil {
+			return javaFile{}, false
+		}
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return javaFile{}, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return javaFile{}, false
+	}
+	return javaFile{path: path, modTime: info.ModTime().UnixNano(), size: info.Size()}, true
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// printEnv writes the effective configuration as KEY=VALUE lines suitable for
+// pasting into a support ticket. Secret-looking values are scrubbed.
+func (w *wrapper) printEnv(exeDir string) {
+	jar, _ := w.resolveJar(exeDir)
+	java, _ := w.resolveJava(exeDir, jar)
+
+	fmt.Fprintf(w.stdout, "WRAPPER_VERSION=%s\n", version)
+	fmt.Fprintf(w.stdout, "JAVA=%s\n", java)
+	fmt.Fprintf(w.stdout, "JAR=%s\n", jar)
+	fmt.Fprintf(w.stdout, "EXE_DIR=%s\n", exeDir)
+	fmt.Fprintf(w.stdout, "OS=%s\n", runtime.GOOS)
+	fmt.Fprintf(w.stdout, "ARCH=%s\n", runtime.GOARCH)
+
+	var vars []string
+	for _, kv := range w.env {
+		if strings.HasPrefix(strings.ToUpper(kv), "BIOVAULT_") {
+			vars = append(vars, w.scrub(kv))
+		}
+	}
+	sort.Strings(vars)
+	for _, kv := range vars {
+		fmt.Fprintln(w.stdout, kv)
+	}
+}